@@ -1,10 +1,14 @@
 package main
 
 import (
+	"github.com/txbrown/gqlgen-api-starter/internal/authz"
 	"github.com/txbrown/gqlgen-api-starter/internal/logger"
 	"github.com/txbrown/gqlgen-api-starter/internal/orm"
 	"github.com/txbrown/gqlgen-api-starter/internal/orm/repositories"
+	"github.com/txbrown/gqlgen-api-starter/internal/pubsub"
+	"github.com/txbrown/gqlgen-api-starter/internal/rbac"
 	"github.com/txbrown/gqlgen-api-starter/internal/services"
+	"github.com/txbrown/gqlgen-api-starter/internal/storage"
 	"github.com/txbrown/gqlgen-api-starter/pkg/server"
 	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
 )
@@ -19,14 +23,43 @@ func main() {
 	userProfilesRepo := repositories.NewUserProfilesRepository(db)
 	rolesRepo := repositories.NewRolesRepository(db)
 	productsRepo := repositories.NewProductsRepository(db)
+	organizationsRepo := repositories.NewOrganizationsRepository(db)
+	auditRepo := repositories.NewAuditRepository(db)
+	groupsRepo := repositories.NewGroupsRepository(db)
+	persistedQueriesRepo := repositories.NewPersistedQueriesRepository(db)
+	filesRepo := repositories.NewFilesRepository(db)
 
-	services := &services.Services{
-		UsersService:    services.NewUsersService(usersRepo, userProfilesRepo, rolesRepo),
-		ProductsService: services.NewProductsService(productsRepo),
+	if err != nil {
+		logger.Panic(err)
 	}
 
+	policy, err := rbac.LoadPolicy(serverconf.RBAC.PolicyFile)
+	if err != nil {
+		logger.Panic(err)
+	}
+	authzEngine, err := authz.NewEngine(policy, authz.RBAC{})
 	if err != nil {
 		logger.Panic(err)
 	}
+
+	events := pubsub.NewInProcess(64)
+
+	store, err := storage.New(serverconf)
+	if err != nil {
+		logger.Panic(err)
+	}
+
+	services := &services.Services{
+		UsersService:            services.NewUsersService(usersRepo, userProfilesRepo, rolesRepo, authzEngine, events),
+		ProductsService:         services.NewProductsService(productsRepo, authzEngine, events),
+		OrganizationsService:    services.NewOrganizationsService(organizationsRepo, usersRepo, rolesRepo, authzEngine),
+		AuditService:            services.NewAuditService(auditRepo, authzEngine),
+		AccessService:           services.NewAccessService(authzEngine, productsRepo, usersRepo, organizationsRepo),
+		GroupsService:           services.NewGroupsService(groupsRepo, usersRepo, rolesRepo, authzEngine),
+		PersistedQueriesService: services.NewPersistedQueriesService(persistedQueriesRepo),
+		FilesService:            services.NewFilesService(filesRepo, store, serverconf.Storage, authzEngine),
+		Policy:                  authzEngine,
+	}
+
 	server.Run(serverconf, services)
 }
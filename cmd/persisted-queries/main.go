@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/persisted"
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/repositories"
+	"github.com/txbrown/gqlgen-api-starter/internal/services"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+)
+
+// Imports the operation manifests of client builds as trusted documents, e.g.
+// persisted-queries -client ios-4.2.0 manifest.json
+func main() {
+	var serverconf = utils.NewServerConfig()
+
+	client := flag.String("client", "", "name of the client build the manifests come from")
+	dryRun := flag.Bool("dry-run", false, "validate the manifests without importing them")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: persisted-queries [-client name] [-dry-run] manifest.json...")
+		os.Exit(2)
+	}
+
+	manifests := map[string][]*models.PersistedQuery{}
+	for _, path := range flag.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		queries, err := persisted.ParseManifest(f, *client)
+		f.Close()
+		if err != nil {
+			logger.Fatalf("%s: %v", path, err)
+		}
+		manifests[path] = queries
+		fmt.Printf("%s: %d operations\n", path, len(queries))
+	}
+	if *dryRun {
+		fmt.Println("\nDry run, no operations were imported.")
+		return
+	}
+
+	db, err := orm.NewDB(serverconf)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	pqs := services.NewPersistedQueriesService(repositories.NewPersistedQueriesRepository(db))
+	for path, queries := range manifests {
+		if err := pqs.Import(queries); err != nil {
+			logger.Fatalf("%s: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm"
+	"github.com/txbrown/gqlgen-api-starter/internal/rbac"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+)
+
+// Syncs the RBAC policy file into the database, or prints the diff with -dry-run
+func main() {
+	var serverconf = utils.NewServerConfig()
+
+	policyFile := flag.String("policy", serverconf.RBAC.PolicyFile, "path to the RBAC policy file")
+	dryRun := flag.Bool("dry-run", false, "print the changes without applying them")
+	flag.Parse()
+
+	policy, err := rbac.LoadPolicy(*policyFile)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	// The sync is done here, don't let the auto migration apply it first
+	serverconf.Database.AutoMigrate = false
+	db, err := orm.NewDB(serverconf)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	plan, err := rbac.Sync(db, policy, *dryRun)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	fmt.Println(plan)
+	if *dryRun && !plan.Empty() {
+		fmt.Println("\nDry run, no changes were applied.")
+	}
+}
@@ -9,10 +9,12 @@ require (
 	github.com/gin-contrib/cors v1.3.1
 	github.com/gin-gonic/gin v1.6.3
 	github.com/gofrs/uuid v4.0.0+incompatible
+	github.com/gorilla/websocket v1.4.2
 	github.com/markbates/goth v1.67.1
 	github.com/sirupsen/logrus v1.8.0
 	github.com/vektah/gqlparser/v2 v2.1.0
 	golang.org/x/crypto v0.0.0-20210218145215-b8e89b74b9df
+	gopkg.in/yaml.v2 v2.2.8
 	gorm.io/driver/postgres v1.0.8
 	gorm.io/gorm v1.20.12
 )
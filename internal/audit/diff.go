@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+)
+
+// Redacted replaces the values of sensitive fields
+const Redacted = "[REDACTED]"
+
+// sensitive matches the keys whose values never reach the trail
+var sensitive = regexp.MustCompile(`(?i)passw|secret|token|api_?key|auth_?code|credential`)
+
+// FieldChange is the before and after value of a changed field
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Diff returns the changed fields between two states of a resource, compared
+// by their JSON representation. Sensitive fields are redacted
+func Diff(before interface{}, after interface{}) map[string]FieldChange {
+	b, a := toMap(before), toMap(after)
+	changes := map[string]FieldChange{}
+	for k, v := range b {
+		if av, ok := a[k]; !ok || !reflect.DeepEqual(v, av) {
+			changes[k] = FieldChange{Before: v, After: a[k]}
+		}
+	}
+	for k, v := range a {
+		if _, ok := b[k]; !ok {
+			changes[k] = FieldChange{After: v}
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// Sanitize returns a copy of the value with its sensitive fields redacted,
+// recursing into maps and lists
+func Sanitize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			if sensitive.MatchString(k) {
+				m[k] = Redacted
+			} else {
+				m[k] = Sanitize(val)
+			}
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(t))
+		for i, val := range t {
+			l[i] = Sanitize(val)
+		}
+		return l
+	}
+	return v
+}
+
+// toMap returns the sanitized JSON object of v, nil if it isn't one
+func toMap(v interface{}) map[string]interface{} {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil
+	}
+	return Sanitize(m).(map[string]interface{})
+}
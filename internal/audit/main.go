@@ -0,0 +1,131 @@
+// Package audit records an append-only trail of the GraphQL mutations and the
+// auth events: who did it, on behalf of whom, what changed and the outcome
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/gofrs/uuid"
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+)
+
+// Events recorded in the trail
+const (
+	EventMutation    = "graphql.mutation"
+	EventLogin       = "auth.login"
+	EventLogout      = "auth.logout"
+	EventAPIKey      = "auth.api_key"
+	EventImpersonate = "auth.impersonate"
+	EventRoleChange  = "auth.role_change"
+	// EventGroupMembership a user joined or left a group, changing its grants
+	EventGroupMembership = "auth.group_membership"
+)
+
+// Outcomes of the recorded events
+const (
+	OutcomeSuccess = "SUCCESS"
+	OutcomeFailure = "FAILURE"
+)
+
+// Recorder appends entries to the trail
+type Recorder interface {
+	Record(e *models.AuditEntry) error
+}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the entry being recorded, resolvers
+// complete it with SetTarget, SetChange and SetEvent
+func NewContext(ctx context.Context, e *models.AuditEntry) context.Context {
+	return context.WithValue(ctx, contextKey{}, e)
+}
+
+// FromContext returns the entry being recorded, if any
+func FromContext(ctx context.Context) *models.AuditEntry {
+	e, _ := ctx.Value(contextKey{}).(*models.AuditEntry)
+	return e
+}
+
+// SetTarget sets the entity and id the recorded mutation acts on
+func SetTarget(ctx context.Context, entity string, id string) {
+	if e := FromContext(ctx); e != nil {
+		e.TargetEntity = entity
+		e.TargetID = id
+	}
+}
+
+// SetChange records the fields that differ between the before and after
+// states of the target, either can be nil for creates and deletes
+func SetChange(ctx context.Context, before interface{}, after interface{}) {
+	if e := FromContext(ctx); e != nil {
+		e.Changes = encode(Diff(before, after))
+	}
+}
+
+// SetEvent overrides the event of the recorded mutation, e.g. EventRoleChange
+func SetEvent(ctx context.Context, event string) {
+	if e := FromContext(ctx); e != nil {
+		e.Event = event
+	}
+}
+
+// NewEntry returns an entry for the event, with the actor, impersonator,
+// organization and client IP of the context
+func NewEntry(ctx context.Context, event string) *models.AuditEntry {
+	e := &models.AuditEntry{Event: event, Outcome: OutcomeSuccess}
+	if u, ok := ctx.Value(utils.ProjectContextKeys.UserCtxKey).(*models.User); ok && u != nil {
+		e.ActorID = idOf(u.ID)
+		e.OrganizationID = u.ActiveOrganizationID
+	}
+	if u, ok := ctx.Value(utils.ProjectContextKeys.ImpersonatorCtxKey).(*models.User); ok && u != nil {
+		e.ImpersonatorID = idOf(u.ID)
+	}
+	if ip, ok := ctx.Value(utils.ProjectContextKeys.ClientIPCtxKey).(string); ok {
+		e.IP = ip
+	}
+	return e
+}
+
+// Fail marks the entry as failed with the error
+func Fail(e *models.AuditEntry, err error) {
+	if err == nil {
+		return
+	}
+	e.Outcome = OutcomeFailure
+	e.Error = err.Error()
+	if len(e.Error) > 1024 {
+		e.Error = e.Error[:1024]
+	}
+}
+
+// Log records the entry, a failure to record is logged but doesn't fail the
+// audited request
+func Log(r Recorder, e *models.AuditEntry) {
+	if r == nil {
+		return
+	}
+	if err := r.Record(e); err != nil {
+		logger.Errorf("[Audit] %s: %v", e.Event, err)
+	}
+}
+
+func idOf(id uuid.UUID) *uuid.UUID {
+	return &id
+}
+
+// encode returns the JSON of v, nil for nil values so the column stays null
+func encode(v interface{}) *string {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Map && reflect.ValueOf(v).IsNil()) {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	s := string(b)
+	return &s
+}
@@ -0,0 +1,44 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// FieldMiddleware records an entry for every root mutation field, with the
+// operation name, the sanitized arguments and the resolver's outcome
+func FieldMiddleware(r Recorder) graphql.FieldMiddleware {
+	return func(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+		fc := graphql.GetFieldContext(ctx)
+		if fc == nil || fc.Object != "Mutation" {
+			return next(ctx)
+		}
+		e := NewEntry(ctx, EventMutation)
+		e.Operation = fc.Field.Name
+		if oc := graphql.GetOperationContext(ctx); oc != nil && oc.OperationName != "" {
+			e.Operation = oc.OperationName + "." + fc.Field.Name
+		}
+		e.Variables = encode(Sanitize(jsonValue(fc.Args)))
+
+		res, err := next(NewContext(ctx, e))
+		Fail(e, err)
+		Log(r, e)
+		return res, err
+	}
+}
+
+// jsonValue converts the resolver arguments, which hold the generated input
+// structs, to plain JSON values
+func jsonValue(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var out interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil
+	}
+	return out
+}
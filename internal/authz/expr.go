@@ -0,0 +1,424 @@
+package authz
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr is a compiled policy condition.
+//
+// The language is deliberately small:
+//   - literals: 1000, 9.99, "text", 'text', true, false, null
+//   - attributes: principal.id, principal.roles, resource.price, ...
+//   - comparisons: == != < <= > >=, and `in` for list membership
+//   - logic: && || ! and parentheses
+type Expr interface {
+	Eval(env map[string]interface{}) (interface{}, error)
+}
+
+// Compile parses a condition into an expression
+func Compile(src string) (Expr, error) {
+	p := &parser{tokens: tokenize(src)}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("condition [%s]: %v", src, err)
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("condition [%s]: unexpected %q", src, t.text)
+	}
+	return e, nil
+}
+
+// EvalBool evaluates the expression, it must result in a boolean
+func EvalBool(e Expr, env map[string]interface{}) (bool, error) {
+	v, err := e.Eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition result is not a boolean: %v", v)
+	}
+	return b, nil
+}
+
+// ## Tokenizer
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+	tokInvalid
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"}
+
+func tokenize(src string) []token {
+	tokens := []token{}
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && rune(src[j]) != c {
+				j++
+			}
+			if j >= len(src) {
+				return append(tokens, token{tokInvalid, src[i:]})
+			}
+			tokens = append(tokens, token{tokString, src[i+1 : j]})
+			i = j + 1
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(src) && unicode.IsDigit(rune(src[i+1]))):
+			j := i + 1
+			for j < len(src) && (unicode.IsDigit(rune(src[j])) || src[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{tokNumber, src[i:j]})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i + 1
+			for j < len(src) && (unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j])) ||
+				src[j] == '_' || src[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{tokIdent, src[i:j]})
+			i = j
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, token{tokOp, op})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return append(tokens, token{tokInvalid, string(c)})
+			}
+		}
+	}
+	return append(tokens, token{tokEOF, ""})
+}
+
+// ## Parser
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); (t.kind == tokOp || t.kind == tokIdent) && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logical{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = logical{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	if p.accept("!") {
+		e, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return not{e}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">", "in"} {
+		if p.accept(op) {
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return comparison{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseOperand() (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return literal{f}, nil
+	case tokString:
+		return literal{t.text}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return literal{true}, nil
+		case "false":
+			return literal{false}, nil
+		case "null":
+			return literal{nil}, nil
+		}
+		return attribute{path: strings.Split(t.text, ".")}, nil
+	case tokOp:
+		if t.text == "(" {
+			e, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.accept(")") {
+				return nil, fmt.Errorf("missing closing parenthesis")
+			}
+			return e, nil
+		}
+	case tokEOF:
+		return nil, fmt.Errorf("unexpected end of condition")
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+// ## Nodes
+
+type literal struct {
+	value interface{}
+}
+
+func (l literal) Eval(env map[string]interface{}) (interface{}, error) {
+	return l.value, nil
+}
+
+type attribute struct {
+	path []string
+}
+
+func (a attribute) Eval(env map[string]interface{}) (interface{}, error) {
+	var v interface{} = env
+	for _, name := range a.path {
+		v = lookup(v, name)
+	}
+	return normalize(v), nil
+}
+
+type not struct {
+	e Expr
+}
+
+func (n not) Eval(env map[string]interface{}) (interface{}, error) {
+	b, err := EvalBool(n.e, env)
+	return !b, err
+}
+
+type logical struct {
+	op          string
+	left, right Expr
+}
+
+func (l logical) Eval(env map[string]interface{}) (interface{}, error) {
+	left, err := EvalBool(l.left, env)
+	if err != nil {
+		return nil, err
+	}
+	if (l.op == "&&" && !left) || (l.op == "||" && left) {
+		return left, nil
+	}
+	return EvalBool(l.right, env)
+}
+
+type comparison struct {
+	op          string
+	left, right Expr
+}
+
+func (c comparison) Eval(env map[string]interface{}) (interface{}, error) {
+	left, err := c.left.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := c.right.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch c.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		list, ok := right.([]interface{})
+		if !ok {
+			return false, nil
+		}
+		for _, item := range list {
+			if equal(left, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	// Ordering, numbers or strings only; anything else (e.g. null) is false
+	if l, ok := left.(float64); ok {
+		if r, ok := right.(float64); ok {
+			return order(c.op, l < r, l == r), nil
+		}
+	}
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			return order(c.op, l < r, l == r), nil
+		}
+	}
+	return false, nil
+}
+
+func order(op string, less bool, eq bool) bool {
+	switch op {
+	case "<":
+		return less
+	case "<=":
+		return less || eq
+	case ">":
+		return !less && !eq
+	case ">=":
+		return !less
+	}
+	return false
+}
+
+func equal(a interface{}, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// lookup resolves an attribute on maps and structs, struct fields are matched
+// case insensitively so `resource.price` resolves `Product.Price`
+func lookup(v interface{}, name string) interface{} {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		f := rv.MapIndex(reflect.ValueOf(name))
+		if !f.IsValid() {
+			return nil
+		}
+		return f.Interface()
+	case reflect.Struct:
+		f := rv.FieldByNameFunc(func(field string) bool {
+			return strings.EqualFold(field, name)
+		})
+		if !f.IsValid() || !f.CanInterface() {
+			return nil
+		}
+		return f.Interface()
+	}
+	return nil
+}
+
+// normalize converts the attribute values to the types the language compares:
+// float64 numbers, strings, bools, nil and []interface{}
+func normalize(v interface{}) interface{} {
+	if s, ok := v.(fmt.Stringer); ok && v != nil {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Ptr || !rv.IsNil() {
+			return s.String()
+		}
+	}
+	rv := reflect.ValueOf(v)
+	for rv.IsValid() && (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Slice, reflect.Array:
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = normalize(rv.Index(i).Interface())
+		}
+		return list
+	}
+	return rv.Interface()
+}
@@ -0,0 +1,85 @@
+// Package authz decides whether a principal can perform an action on a
+// resource. Attribute based rules from the policy file are evaluated first,
+// the RBAC permissions of the principal are the default policy
+package authz
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
+)
+
+// Request is what a policy is asked to decide on
+type Request struct {
+	Principal *models.User
+	Action    string      // Permission format, e.g. consts.Permissions.Update
+	Entity    string      // Entity name, e.g. consts.EntityNames.Products
+	Resource  interface{} // The loaded resource, nil for list/create actions
+}
+
+// Tag returns the action:entity permission tag of the request
+func (r Request) Tag() string {
+	return fmt.Sprintf(r.Action, consts.GetTableName(r.Entity))
+}
+
+// Decision is the outcome of a policy evaluation
+type Decision struct {
+	Allowed bool
+	Reason  string
+}
+
+// Policy evaluates authorization requests
+type Policy interface {
+	Evaluate(r Request) Decision
+}
+
+// DeniedError is returned by Authorize when the policy denies the request
+type DeniedError struct {
+	Tag    string
+	Reason string
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("[%s] denied: %s", e.Tag, e.Reason)
+}
+
+// Check evaluates the request against the policy. It's the single code path
+// of the authorization decisions, clients asking what they can do included
+func Check(p Policy, principal *models.User, action string, entity string, resource interface{}) (Request, Decision) {
+	r := Request{Principal: principal, Action: action, Entity: entity, Resource: resource}
+	return r, p.Evaluate(r)
+}
+
+// Authorize evaluates the request against the policy, returning a
+// *DeniedError when it is not allowed
+func Authorize(p Policy, principal *models.User, action string, entity string, resource interface{}) error {
+	if r, d := Check(p, principal, action, entity, resource); !d.Allowed {
+		return &DeniedError{Tag: r.Tag(), Reason: d.Reason}
+	}
+	return nil
+}
+
+// RBAC is the default policy, it allows the request if the principal holds
+// the action:entity permission
+type RBAC struct{}
+
+// Evaluate checks the principal's permissions
+func (RBAC) Evaluate(r Request) Decision {
+	if r.Principal == nil {
+		return Decision{Reason: "unauthenticated"}
+	}
+	if ok, err := r.Principal.HasPermission(r.Action, r.Entity); !ok {
+		return Decision{Reason: err.Error()}
+	}
+	return Decision{Allowed: true, Reason: "rbac: permission " + r.Tag()}
+}
+
+func splitTag(tag string) (action string, entity string) {
+	parts := strings.SplitN(tag, ":", 2)
+	if len(parts) != 2 {
+		return tag, ""
+	}
+	return parts[0], parts[1]
+}
@@ -0,0 +1,123 @@
+package authz
+
+import (
+	"fmt"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/internal/rbac"
+)
+
+// Engine evaluates the attribute based rules of the policy file. Deny rules
+// win over allow rules; when no rule applies the fallback policy decides
+type Engine struct {
+	rules    []*rule
+	fallback Policy
+}
+
+type rule struct {
+	rbac.RuleDef
+	condition Expr
+}
+
+// NewEngine compiles the rules of the policy, fallback is usually RBAC{}
+func NewEngine(policy *rbac.Policy, fallback Policy) (*Engine, error) {
+	e := &Engine{fallback: fallback}
+	for i, def := range policy.Rules {
+		r := &rule{RuleDef: def}
+		if def.Condition != "" {
+			c, err := Compile(def.Condition)
+			if err != nil {
+				return nil, fmt.Errorf("[Authz.NewEngine] rule #%d: %v", i+1, err)
+			}
+			r.condition = c
+		}
+		e.rules = append(e.rules, r)
+	}
+	return e, nil
+}
+
+// Evaluate decides on the request
+func (e *Engine) Evaluate(r Request) Decision {
+	if r.Principal == nil {
+		return Decision{Reason: "unauthenticated"}
+	}
+	action, entity := splitTag(r.Tag())
+	env := environment(r)
+
+	var allowed *rule
+	for _, rl := range e.rules {
+		if !rl.applies(r, action, entity) {
+			continue
+		}
+		ok, err := rl.holds(env)
+		if err != nil {
+			logger.Errorf("[Authz.Engine] rule [%s]: %v", rl.Description, err)
+			return Decision{Reason: "rule [" + rl.Description + "] could not be evaluated"}
+		}
+		if !ok {
+			continue
+		}
+		if rl.Effect == "deny" {
+			return Decision{Reason: "denied by rule: " + rl.Description}
+		}
+		if allowed == nil {
+			allowed = rl
+		}
+	}
+	if allowed != nil {
+		return Decision{Allowed: true, Reason: "allowed by rule: " + allowed.Description}
+	}
+	return e.fallback.Evaluate(r)
+}
+
+func (rl *rule) applies(r Request, action string, entity string) bool {
+	if rl.Entity != entity || !contains(rl.Actions, action) {
+		return false
+	}
+	if len(rl.Roles) == 0 {
+		return true
+	}
+	for _, role := range r.Principal.Roles {
+		if contains(rl.Roles, role.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rl *rule) holds(env map[string]interface{}) (bool, error) {
+	if rl.condition == nil {
+		return true, nil
+	}
+	return EvalBool(rl.condition, env)
+}
+
+// environment exposes the principal and the resource to the conditions
+func environment(r Request) map[string]interface{} {
+	roles := []interface{}{}
+	for _, role := range r.Principal.Roles {
+		roles = append(roles, role.Name)
+	}
+	permissions := []interface{}{}
+	for _, tag := range r.Principal.PermissionTags() {
+		permissions = append(permissions, tag)
+	}
+	return map[string]interface{}{
+		"principal": map[string]interface{}{
+			"id":          r.Principal.ID.String(),
+			"email":       r.Principal.Email,
+			"roles":       roles,
+			"permissions": permissions,
+		},
+		"resource": r.Resource,
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package gql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/common"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/transformations"
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
+)
+
+func (r *queryResolver) AuditLog(ctx context.Context, filters []*model.QueryFilter, where *model.FilterGroup, limit *int, offset *int) (*model.AuditLog, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	dbRecords, more, err := r.Services.AuditService.List(cu, filters, where, limit, offset)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.AuditEntries, err)
+	}
+
+	record := &model.AuditLog{HasNextPage: more, List: []*model.AuditEntry{}}
+	for _, dbRec := range dbRecords {
+		record.List = append(record.List, transformations.DBAuditEntryToGQLAuditEntry(dbRec))
+	}
+	if isSelected(ctx, "count") {
+		count, err := r.Services.AuditService.Count(cu, filters, where)
+		if err != nil {
+			return nil, logger.Errorfn(consts.EntityNames.AuditEntries, err)
+		}
+		total := int(count)
+		record.Count = &total
+	}
+	return record, nil
+}
@@ -9,6 +9,22 @@ import (
 	"github.com/vektah/gqlparser/v2/gqlerror"
 )
 
+// The extensions.code of the errors returned to clients
+const (
+	// CodeUnauthenticated the operation needs a signed in user
+	CodeUnauthenticated = "UNAUTHENTICATED"
+	// CodeForbidden the user is not allowed to do it
+	CodeForbidden = "FORBIDDEN"
+	// CodeNotFound the entity doesn't exist, or the user can't see it
+	CodeNotFound = "NOT_FOUND"
+	// CodeValidation the input is invalid, the message says why
+	CodeValidation = "VALIDATION"
+	// CodeConflict the change conflicts with existing data, e.g. a duplicate
+	CodeConflict = "CONFLICT"
+	// CodeInternal anything else, details are only logged
+	CodeInternal = "INTERNAL"
+)
+
 // GqlServerError - Graphql Internal Server Error
 func GqlServerError(ctx context.Context) error {
 	return &gqlerror.Error{
@@ -16,6 +32,7 @@ func GqlServerError(ctx context.Context) error {
 		Message: "Internal server error.",
 		Extensions: map[string]interface{}{
 			"statusCode": http.StatusInternalServerError,
+			"code":       CodeInternal,
 		}}
 }
 
@@ -26,6 +43,7 @@ func GqlUserConflictError(ctx context.Context) error {
 		Message: "A user with this email already exists",
 		Extensions: map[string]interface{}{
 			"statusCode": http.StatusConflict,
+			"code":       CodeConflict,
 		}}
 }
 
@@ -36,6 +54,7 @@ func GqlForbiddenError(ctx context.Context) error {
 		Message: "Forbidden",
 		Extensions: map[string]interface{}{
 			"statusCode": http.StatusForbidden,
+			"code":       CodeForbidden,
 		}}
 
 	return err
@@ -48,6 +67,7 @@ func GqlUnauthorizedError(ctx context.Context) error {
 		Message: "Unauthorized",
 		Extensions: map[string]interface{}{
 			"statusCode": http.StatusUnauthorized,
+			"code":       CodeUnauthenticated,
 		}}
 
 	return err
@@ -59,6 +79,7 @@ func GqlBadRequestError(ctx context.Context) error {
 		Message: "Bad request",
 		Extensions: map[string]interface{}{
 			"statusCode": http.StatusBadRequest,
+			"code":       CodeValidation,
 		}}
 
 	return err
@@ -70,11 +91,23 @@ func GqlNotFoundRequestError(ctx context.Context) error {
 		Message: "Not found",
 		Extensions: map[string]interface{}{
 			"statusCode": http.StatusNotFound,
+			"code":       CodeNotFound,
 		}}
 
 	return err
 }
 
+// GqlNotImplementedError - The operation is declared but not implemented yet
+func GqlNotImplementedError(ctx context.Context) error {
+	return &gqlerror.Error{
+		Path:    getPath(ctx),
+		Message: "Not implemented",
+		Extensions: map[string]interface{}{
+			"statusCode": http.StatusNotImplemented,
+			"code":       CodeInternal,
+		}}
+}
+
 func getPath(ctx context.Context) ast.Path {
 	return graphql.GetPath(ctx)
 }
@@ -0,0 +1,173 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/gofrs/uuid"
+	"github.com/txbrown/gqlgen-api-starter/internal/authz"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/relay"
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/pagination"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/query"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/tenancy"
+	"github.com/txbrown/gqlgen-api-starter/internal/services"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"gorm.io/gorm"
+)
+
+// codeMessages replace the messages of the errors in production, the
+// messages of the other codes are meant for the client
+var codeMessages = map[string]string{
+	CodeUnauthenticated: "Unauthorized",
+	CodeForbidden:       "Forbidden",
+	CodeNotFound:        "Not found",
+	CodeConflict:        "Conflict",
+	CodeInternal:        "Internal server error",
+}
+
+// sqlStateCodes maps the Postgres error classes clients can cause to codes
+var sqlStateCodes = map[string]string{
+	"23505": CodeConflict,   // unique_violation
+	"23503": CodeConflict,   // foreign_key_violation
+	"23502": CodeValidation, // not_null_violation
+	"23514": CodeValidation, // check_violation
+	"22P02": CodeValidation, // invalid_text_representation
+	"22001": CodeValidation, // string_data_right_truncation
+}
+
+type registered struct {
+	err  error
+	code string
+}
+
+var (
+	mu    sync.RWMutex
+	codes = []registered{
+		{gorm.ErrRecordNotFound, CodeNotFound},
+		{models.ErrAuditAppendOnly, CodeForbidden},
+		{tenancy.ErrNoOrganization, CodeForbidden},
+		{services.ErrInvalidID, CodeValidation},
+		{relay.ErrInvalidGlobalID, CodeValidation},
+		{services.ErrUnknownEntity, CodeValidation},
+		{services.ErrUnknownRole, CodeValidation},
+		{services.ErrUnknownPermission, CodeValidation},
+		{services.ErrNotMember, CodeValidation},
+		{services.ErrPlatformRole, CodeForbidden},
+		{services.ErrGroupCycle, CodeValidation},
+		{services.ErrGroupTooDeep, CodeValidation},
+		{services.ErrInvalidCurrentPassword, CodeValidation},
+		{services.ErrEmptyPassword, CodeValidation},
+		{services.ErrCredentialsNotOwned, CodeForbidden},
+		{services.ErrFileTooLarge, CodeValidation},
+		{services.ErrFileTypeNotAllowed, CodeValidation},
+		{services.ErrFileSizeMismatch, CodeValidation},
+		{query.ErrInvalidFilter, CodeValidation},
+		{query.ErrInvalidSort, CodeValidation},
+		{query.ErrInvalidLimit, CodeValidation},
+		{pagination.ErrInvalidCursor, CodeValidation},
+		{pagination.ErrInvalidPageSize, CodeValidation},
+		{pagination.ErrFirstAndLast, CodeValidation},
+		{pagination.ErrInvalidOrder, CodeValidation},
+	}
+)
+
+// RegisterCode maps the domain errors, and the errors wrapping them, to the
+// code
+func RegisterCode(code string, errs ...error) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, err := range errs {
+		codes = append(codes, registered{err: err, code: code})
+	}
+}
+
+// Code returns the code of an error, INTERNAL when it is not a known domain
+// error
+func Code(err error) string {
+	var denied *authz.DeniedError
+	if errors.As(err, &denied) {
+		return CodeForbidden
+	}
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		if code, ok := sqlStateCodes[pgErr.SQLState()]; ok {
+			return code
+		}
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, r := range codes {
+		if errors.Is(err, r.err) {
+			return r.code
+		}
+	}
+	return CodeInternal
+}
+
+// ErrorPresenter gives every error its code and a correlation id, logged with
+// the error so a client report can be matched with the server logs. In
+// production the messages of the internal errors are hidden
+func ErrorPresenter(cfg *utils.ServerConfig) graphql.ErrorPresenterFunc {
+	return func(ctx context.Context, e error) *gqlerror.Error {
+		var gqlErr *gqlerror.Error
+		if !errors.As(e, &gqlErr) {
+			gqlErr = gqlerror.WrapPath(graphql.GetPath(ctx), e)
+		}
+		presented := *gqlErr
+		presented.Extensions = map[string]interface{}{}
+		for k, v := range gqlErr.Extensions {
+			presented.Extensions[k] = v
+		}
+
+		code, preset := presented.Extensions["code"].(string)
+		var id string
+		var p *panicError
+		if errors.As(e, &p) {
+			code, preset, id = CodeInternal, false, p.id
+		} else if !preset {
+			code = Code(e)
+		}
+		if id == "" {
+			id = newCorrelationID()
+			if code == CodeInternal {
+				logger.Errorf("[GraphQL] correlationId=%s code=%s path=%s: %v", id, code, presented.Path, e)
+			} else {
+				logger.Infof("[GraphQL] correlationId=%s code=%s path=%s: %v", id, code, presented.Path, e)
+			}
+		}
+		presented.Extensions["code"] = code
+		presented.Extensions["correlationId"] = id
+
+		if msg, ok := codeMessages[code]; ok && !preset && cfg.IsProduction() {
+			presented.Message = msg
+		}
+		return &presented
+	}
+}
+
+// Recover logs the panics of the resolvers, with their stack, and turns them
+// into internal errors
+func Recover(ctx context.Context, r interface{}) error {
+	id := newCorrelationID()
+	logger.Errorf("[GraphQL] correlationId=%s code=%s panic: %v\n%s", id, CodeInternal, r, debug.Stack())
+	return &panicError{id: id}
+}
+
+// panicError is a recovered panic, already logged under its correlation id
+type panicError struct {
+	id string
+}
+
+func (e *panicError) Error() string {
+	return "internal system error"
+}
+
+func newCorrelationID() string {
+	return uuid.Must(uuid.NewV4()).String()
+}
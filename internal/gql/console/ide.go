@@ -0,0 +1,139 @@
+package console
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+)
+
+// The IDEs that can be embedded
+const (
+	IDEPlayground = "playground"
+	IDEGraphiQL   = "graphiql"
+	IDESandbox    = "sandbox"
+)
+
+var ides = map[string]*template.Template{
+	IDEPlayground: template.Must(template.New(IDEPlayground).Parse(playgroundPage)),
+	IDEGraphiQL:   template.Must(template.New(IDEGraphiQL).Parse(graphiqlPage)),
+	IDESandbox:    template.Must(template.New(IDESandbox).Parse(sandboxPage)),
+}
+
+// IDE returns the handler of the IDE's page for the endpoint, the users the
+// access doesn't allow get a not found. The IDE sends its requests with the
+// cookies of the user's session, the page itself carries no credentials
+func IDE(ide string, title string, endpoint string, access Access) (http.HandlerFunc, error) {
+	page, ok := ides[ide]
+	if !ok {
+		return nil, fmt.Errorf("[Console] unknown IDE: %s, expected %s, %s or %s", ide, IDEPlayground, IDEGraphiQL, IDESandbox)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		cu, _ := r.Context().Value(utils.ProjectContextKeys.UserCtxKey).(*models.User)
+		if !access.Allows(cu) {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		err := page.Execute(w, map[string]interface{}{
+			"title":    title,
+			"endpoint": endpoint,
+		})
+		if err != nil {
+			panic(err)
+		}
+	}, nil
+}
+
+const playgroundPage = `<!DOCTYPE html>
+<html>
+<head>
+	<meta charset=utf-8/>
+	<meta name="viewport" content="user-scalable=no, initial-scale=1.0, minimum-scale=1.0, maximum-scale=1.0, minimal-ui">
+	<link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/graphql-playground-react@1.7.20/build/static/css/index.css"
+		integrity="sha256-cS9Vc2OBt9eUf4sykRWukeFYaInL29+myBmFDSa7F/U=" crossorigin="anonymous"/>
+	<link rel="shortcut icon" href="https://cdn.jsdelivr.net/npm/graphql-playground-react@1.7.20/build/favicon.png"
+		integrity="sha256-GhTyE+McTU79R4+pRO6ih+4TfsTOrpPwD8ReKFzb3PM=" crossorigin="anonymous"/>
+	<script src="https://cdn.jsdelivr.net/npm/graphql-playground-react@1.7.20/build/static/js/middleware.js"
+		integrity="sha256-4QG1Uza2GgGdlBL3RCBCGtGeZB6bDbsw8OltCMGeJsA=" crossorigin="anonymous"></script>
+	<title>{{.title}}</title>
+</head>
+<body>
+<style type="text/css">
+	html { font-family: "Open Sans", sans-serif; overflow: hidden; }
+	body { margin: 0; background: #172a3a; }
+</style>
+<div id="root"/>
+<script type="text/javascript">
+	window.addEventListener('load', function (event) {
+		const root = document.getElementById('root');
+		root.classList.add('playgroundIn');
+		const wsProto = location.protocol == 'https:' ? 'wss:' : 'ws:';
+		const endpoint = location.protocol + '//' + location.host + {{.endpoint}};
+		GraphQLPlayground.init(root, {
+			endpoint: endpoint,
+			subscriptionsEndpoint: wsProto + '//' + location.host + {{.endpoint}},
+			settings: {
+				'request.credentials': 'include'
+			}
+		});
+	});
+</script>
+</body>
+</html>
+`
+
+const graphiqlPage = `<!DOCTYPE html>
+<html>
+<head>
+	<meta charset=utf-8/>
+	<title>{{.title}}</title>
+	<link rel="stylesheet" href="https://unpkg.com/graphiql@1.4.7/graphiql.min.css"/>
+	<script src="https://unpkg.com/react@17/umd/react.production.min.js" crossorigin="anonymous"></script>
+	<script src="https://unpkg.com/react-dom@17/umd/react-dom.production.min.js" crossorigin="anonymous"></script>
+	<script src="https://unpkg.com/graphiql@1.4.7/graphiql.min.js" crossorigin="anonymous"></script>
+</head>
+<body style="margin: 0;">
+<div id="graphiql" style="height: 100vh;"></div>
+<script type="text/javascript">
+	const wsProto = location.protocol == 'https:' ? 'wss:' : 'ws:';
+	const fetcher = GraphiQL.createFetcher({
+		url: location.protocol + '//' + location.host + {{.endpoint}},
+		subscriptionUrl: wsProto + '//' + location.host + {{.endpoint}},
+		fetch: function (url, options) {
+			return window.fetch(url, Object.assign({}, options, { credentials: 'include' }));
+		}
+	});
+	ReactDOM.render(
+		React.createElement(GraphiQL, {
+			fetcher: fetcher,
+			headerEditorEnabled: true
+		}),
+		document.getElementById('graphiql')
+	);
+</script>
+</body>
+</html>
+`
+
+const sandboxPage = `<!DOCTYPE html>
+<html>
+<head>
+	<meta charset=utf-8/>
+	<title>{{.title}}</title>
+</head>
+<body style="margin: 0;">
+<div id="embedded-sandbox" style="width: 100vw; height: 100vh;"></div>
+<script src="https://embeddable-sandbox.cdn.apollographql.com/_latest/embeddable-sandbox.umd.production.min.js"></script>
+<script type="text/javascript">
+	new window.EmbeddedSandbox({
+		target: '#embedded-sandbox',
+		initialEndpoint: location.protocol + '//' + location.host + {{.endpoint}},
+		includeCookies: true
+	});
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,65 @@
+// Package console controls the developer tools of the GraphQL endpoint: who
+// can introspect the schema and who can open the embedded IDE. Each is open to
+// everyone when enabled, otherwise to the users holding one of its roles
+package console
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Access is who can use a tool
+type Access struct {
+	Enabled bool
+	Roles   []string
+}
+
+// Allows reports whether the user, nil when anonymous, can use the tool
+func (a Access) Allows(u *models.User) bool {
+	if a.Enabled {
+		return true
+	}
+	if u == nil {
+		return false
+	}
+	for _, role := range a.Roles {
+		if u.HasRoleName(role) {
+			return true
+		}
+	}
+	return false
+}
+
+var _ interface {
+	graphql.OperationContextMutator
+	graphql.HandlerExtension
+} = Introspection{}
+
+// Introspection turns introspection on for the operations of the users it
+// allows, it is off for the others
+type Introspection struct {
+	Access Access
+}
+
+// ExtensionName the name of the extension
+func (i Introspection) ExtensionName() string {
+	return "Introspection"
+}
+
+// Validate the extension needs no schema
+func (i Introspection) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+// MutateOperationContext allows introspection for the operation's user
+func (i Introspection) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	cu, _ := ctx.Value(utils.ProjectContextKeys.UserCtxKey).(*models.User)
+	if i.Access.Allows(cu) {
+		rc.DisableIntrospection = false
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package constraints
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid"
+)
+
+// formats are the named formats of the strings
+var formats = map[string]func(s string) bool{
+	"email": func(s string) bool {
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	},
+	"uuid": func(s string) bool {
+		_, err := uuid.FromString(s)
+		return err == nil
+	},
+	"url": func(s string) bool {
+		u, err := url.ParseRequestURI(s)
+		return err == nil && u.Scheme != "" && u.Host != ""
+	},
+}
+
+// constraint is a @constraint of an input field. Lengths count the characters
+// of strings and the items of lists, the other checks apply to the strings
+// and numbers, or to each of them in a list
+type constraint struct {
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+	format    string
+	min       *float64
+	max       *float64
+}
+
+// failure is a check of a constraint a value doesn't pass
+type failure struct {
+	constraint string
+	message    string
+}
+
+func newConstraint(args map[string]interface{}) (*constraint, error) {
+	c := &constraint{}
+	if v, ok := toFloat(args["minLength"]); ok {
+		n := int(v)
+		c.minLength = &n
+	}
+	if v, ok := toFloat(args["maxLength"]); ok {
+		n := int(v)
+		c.maxLength = &n
+	}
+	if p, ok := args["pattern"].(string); ok {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", p, err)
+		}
+		c.pattern = re
+	}
+	if f, ok := args["format"].(string); ok {
+		if formats[f] == nil {
+			return nil, fmt.Errorf("unknown format %q", f)
+		}
+		c.format = f
+	}
+	if v, ok := toFloat(args["min"]); ok {
+		c.min = &v
+	}
+	if v, ok := toFloat(args["max"]); ok {
+		c.max = &v
+	}
+	return c, nil
+}
+
+// check returns the checks the value fails
+func (c *constraint) check(v interface{}) []failure {
+	failures := []failure{}
+	if list, ok := v.([]interface{}); ok {
+		failures = append(failures, c.length(len(list), "items")...)
+		for _, item := range list {
+			failures = append(failures, c.scalar(item)...)
+		}
+		return failures
+	}
+	if s, ok := v.(string); ok {
+		failures = append(failures, c.length(utf8.RuneCountInString(s), "characters")...)
+	}
+	return append(failures, c.scalar(v)...)
+}
+
+func (c *constraint) length(n int, unit string) []failure {
+	failures := []failure{}
+	if c.minLength != nil && n < *c.minLength {
+		failures = append(failures, failure{"minLength", fmt.Sprintf("must have at least %d %s", *c.minLength, unit)})
+	}
+	if c.maxLength != nil && n > *c.maxLength {
+		failures = append(failures, failure{"maxLength", fmt.Sprintf("must have at most %d %s", *c.maxLength, unit)})
+	}
+	return failures
+}
+
+func (c *constraint) scalar(v interface{}) []failure {
+	failures := []failure{}
+	if s, ok := v.(string); ok {
+		if c.pattern != nil && !c.pattern.MatchString(s) {
+			failures = append(failures, failure{"pattern", fmt.Sprintf("must match %s", c.pattern)})
+		}
+		if c.format != "" && !formats[c.format](s) {
+			failures = append(failures, failure{"format", fmt.Sprintf("must be a valid %s", c.format)})
+		}
+		return failures
+	}
+	if n, ok := toFloat(v); ok {
+		if c.min != nil && n < *c.min {
+			failures = append(failures, failure{"min", fmt.Sprintf("must be at least %v", *c.min)})
+		}
+		if c.max != nil && n > *c.max {
+			failures = append(failures, failure{"max", fmt.Sprintf("must be at most %v", *c.max)})
+		}
+	}
+	return failures
+}
+
+// toFloat reads a number of a literal or a variable
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
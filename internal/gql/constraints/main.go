@@ -0,0 +1,164 @@
+// Package constraints enforces the @constraint directives of the input fields
+// before the operation is executed, so the resolvers only see valid inputs.
+// All the violations of an operation are reported together, in a single
+// VALIDATION error listing the path of each offending field
+package constraints
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/common"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Directive is the name of the constraint directive in the schema
+const Directive = "constraint"
+
+var _ interface {
+	graphql.OperationContextMutator
+	graphql.HandlerExtension
+} = &Extension{}
+
+// Violation is an input value that breaks a constraint of its field
+type Violation struct {
+	Path       string `json:"path"`
+	Constraint string `json:"constraint"`
+	Message    string `json:"message"`
+}
+
+// Extension checks the arguments of the operation's fields against the
+// constraints of the schema
+type Extension struct {
+	schema      *ast.Schema
+	constraints map[*ast.FieldDefinition]*constraint
+}
+
+// ExtensionName the name of the extension
+func (e Extension) ExtensionName() string {
+	return "InputConstraints"
+}
+
+// Validate reads the constraints of the schema's input fields, a constraint
+// that can't be enforced, e.g. a bad pattern, is an error
+func (e *Extension) Validate(schema graphql.ExecutableSchema) error {
+	e.schema = schema.Schema()
+	e.constraints = map[*ast.FieldDefinition]*constraint{}
+	for _, def := range e.schema.Types {
+		if def.Kind != ast.InputObject {
+			continue
+		}
+		for _, f := range def.Fields {
+			d := f.Directives.ForName(Directive)
+			if d == nil {
+				continue
+			}
+			c, err := newConstraint(d.ArgumentMap(nil))
+			if err != nil {
+				return fmt.Errorf("[Constraints] %s.%s: %v", def.Name, f.Name, err)
+			}
+			e.constraints[f] = c
+		}
+	}
+	return nil
+}
+
+// MutateOperationContext rejects the operation if any of its inputs breaks a
+// constraint
+func (e Extension) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	if len(e.constraints) == 0 {
+		return nil
+	}
+	op := rc.Doc.Operations.ForName(rc.OperationName)
+	w := walker{Extension: e, vars: rc.Variables, seen: map[string]bool{}}
+	w.selections(nil, op.SelectionSet)
+	if len(w.violations) == 0 {
+		return nil
+	}
+	return &gqlerror.Error{
+		Message: fmt.Sprintf("input has %d constraint violation(s)", len(w.violations)),
+		Extensions: map[string]interface{}{
+			"code":       common.CodeValidation,
+			"violations": w.violations,
+		},
+	}
+}
+
+// walker collects the violations of the arguments of an operation
+type walker struct {
+	Extension
+	vars       map[string]interface{}
+	seen       map[string]bool
+	violations []Violation
+}
+
+func (w *walker) selections(path []string, selectionSet ast.SelectionSet) {
+	for _, selection := range selectionSet {
+		switch s := selection.(type) {
+		case *ast.Field:
+			if s.Definition == nil {
+				continue
+			}
+			fieldPath := append(path[:len(path):len(path)], s.Alias)
+			args := s.ArgumentMap(w.vars)
+			for _, arg := range s.Definition.Arguments {
+				w.value(strings.Join(append(fieldPath, arg.Name), "."), arg.Type, args[arg.Name])
+			}
+			w.selections(fieldPath, s.SelectionSet)
+		case *ast.InlineFragment:
+			w.selections(path, s.SelectionSet)
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				w.selections(path, s.Definition.SelectionSet)
+			}
+		}
+	}
+}
+
+// value checks the input objects of the value, and their lists, field by
+// field
+func (w *walker) value(path string, t *ast.Type, v interface{}) {
+	if v == nil {
+		return
+	}
+	if t.Elem != nil {
+		list, _ := v.([]interface{})
+		for i, item := range list {
+			w.value(path+"["+strconv.Itoa(i)+"]", t.Elem, item)
+		}
+		return
+	}
+	def := w.schema.Types[t.NamedType]
+	obj, ok := v.(map[string]interface{})
+	if def == nil || def.Kind != ast.InputObject || !ok {
+		return
+	}
+	for _, f := range def.Fields {
+		fv, ok := obj[f.Name]
+		if !ok || fv == nil {
+			continue
+		}
+		fieldPath := path + "." + f.Name
+		if c := w.constraints[f]; c != nil {
+			for _, failed := range c.check(fv) {
+				w.add(Violation{Path: fieldPath, Constraint: failed.constraint, Message: failed.message})
+			}
+		}
+		w.value(fieldPath, f.Type, fv)
+	}
+}
+
+// add keeps the violation, once, a fragment spread twice at the same level
+// reaches the same arguments again
+func (w *walker) add(v Violation) {
+	key := v.Path + "\x00" + v.Constraint
+	if w.seen[key] {
+		return
+	}
+	w.seen[key] = true
+	w.violations = append(w.violations, v)
+}
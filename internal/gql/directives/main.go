@@ -0,0 +1,16 @@
+// Package directives implements the schema directives, they are enforced by the
+// gqlgen executor around the field resolvers they are declared on
+package directives
+
+import (
+	"github.com/txbrown/gqlgen-api-starter/internal/authz"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/generated"
+)
+
+// Root returns the directive implementations for the executable schema config,
+// the permissions they check are decided by the policy
+func Root(policy authz.Policy) generated.DirectiveRoot {
+	return generated.DirectiveRoot{
+		Restricted: Restricted(policy),
+	}
+}
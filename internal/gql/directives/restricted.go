@@ -0,0 +1,51 @@
+package directives
+
+import (
+	"context"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/txbrown/gqlgen-api-starter/internal/authz"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/common"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+)
+
+// RestrictedFunc is the signature of the @restricted directive
+type RestrictedFunc func(ctx context.Context, obj interface{}, next graphql.Resolver,
+	permission string, allowOwner *bool, policy *model.RestrictionPolicy) (interface{}, error)
+
+// Restricted implements the @restricted directive, the field is only resolved
+// for the owner of the parent object or for users the policy allows the
+// permission tag on it
+func Restricted(p authz.Policy) RestrictedFunc {
+	return func(ctx context.Context, obj interface{}, next graphql.Resolver,
+		permission string, allowOwner *bool, policy *model.RestrictionPolicy) (interface{}, error) {
+		if canSee(ctx, p, obj, permission, allowOwner == nil || *allowOwner) {
+			return next(ctx)
+		}
+		if policy != nil && *policy == model.RestrictionPolicyError {
+			return nil, common.GqlForbiddenError(ctx)
+		}
+		return nil, nil
+	}
+}
+
+func canSee(ctx context.Context, p authz.Policy, obj interface{}, permission string, allowOwner bool) bool {
+	cu, ok := ctx.Value(utils.ProjectContextKeys.UserCtxKey).(*models.User)
+	if !ok || cu == nil {
+		return false
+	}
+	if allowOwner {
+		if o, ok := obj.(model.Owned); ok && o.OwnerID() == cu.ID.String() {
+			return true
+		}
+	}
+	// the permission is an action:entity tag
+	parts := strings.SplitN(permission, ":", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	return authz.Authorize(p, cu, parts[0]+":%s", parts[1], obj) == nil
+}
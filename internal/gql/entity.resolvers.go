@@ -0,0 +1,48 @@
+package gql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/generated"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/loaders"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/relay"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/transformations"
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
+)
+
+func (r *entityResolver) FindProductByID(ctx context.Context, id string) (*model.Product, error) {
+	key, err := relay.Key(id, relay.TypeProduct)
+	if err != nil {
+		return nil, err
+	}
+	dbo, err := loaders.FromContext(ctx).Product(key)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Products, err)
+	}
+	if dbo == nil {
+		return nil, nil
+	}
+	return transformations.DBProductToGQLProduct(dbo), nil
+}
+
+func (r *entityResolver) FindUserByID(ctx context.Context, id string) (*model.User, error) {
+	key, err := relay.Key(id, relay.TypeUser)
+	if err != nil {
+		return nil, err
+	}
+	dbo, err := loaders.FromContext(ctx).User(key)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Users, err)
+	}
+	return transformations.DBUserToGQLUser(dbo), nil
+}
+
+// Entity returns generated.EntityResolver implementation.
+func (r *Resolver) Entity() generated.EntityResolver { return &entityResolver{r} }
+
+type entityResolver struct{ *Resolver }
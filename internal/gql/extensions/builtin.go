@@ -0,0 +1,60 @@
+package extensions
+
+import (
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/limits"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/persisted"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/ratelimit"
+	"github.com/txbrown/gqlgen-api-starter/internal/services"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+)
+
+// The extensions of the server's own features
+const (
+	PersistedQueries = "persisted_queries"
+	DepthLimit       = "depth_limit"
+	ComplexityLimit  = "complexity_limit"
+	RateLimit        = "rate_limit"
+)
+
+func init() {
+	Register(PersistedQueries, persistedQueries)
+	Register(DepthLimit, depthLimit)
+	Register(ComplexityLimit, complexityLimit)
+	Register(RateLimit, rateLimit)
+}
+
+// persistedQueries serves trusted documents only, or automatic persisted
+// queries, or neither
+func persistedQueries(cfg *utils.ServerConfig, services *services.Services) (graphql.HandlerExtension, error) {
+	pq := cfg.GraphQL.PersistedQueries
+	if pq.TrustedOnly {
+		return persisted.NewTrusted(pq.CacheSize, services.PersistedQueriesService), nil
+	}
+	if !pq.Enabled {
+		return nil, nil
+	}
+	store := services.PersistedQueriesService
+	if !pq.SharedStore {
+		store = nil
+	}
+	return extension.AutomaticPersistedQuery{Cache: persisted.NewCache(pq.CacheSize, store)}, nil
+}
+
+func depthLimit(cfg *utils.ServerConfig, services *services.Services) (graphql.HandlerExtension, error) {
+	return limits.Depth{Limit: cfg.GraphQL.Limits.MaxDepth}, nil
+}
+
+func complexityLimit(cfg *utils.ServerConfig, services *services.Services) (graphql.HandlerExtension, error) {
+	return &limits.Complexity{Budget: limits.Budget(cfg.GraphQL.Limits)}, nil
+}
+
+// rateLimit keeps the buckets in memory, each server limits on its own
+func rateLimit(cfg *utils.ServerConfig, services *services.Services) (graphql.HandlerExtension, error) {
+	tiers, err := ratelimit.ParseTiers(cfg.GraphQL.RateLimit.Tiers)
+	if err != nil {
+		return nil, err
+	}
+	return &ratelimit.Limiter{Store: ratelimit.NewMemoryStore(), Tiers: tiers, FailOpen: cfg.GraphQL.RateLimit.FailOpen}, nil
+}
@@ -0,0 +1,72 @@
+// Package extensions registers the gqlgen extensions the server can be
+// configured with. Server features plug in here, by name, instead of as gin
+// middleware, and GQL_SERVER_EXTENSIONS picks the ones that are used
+package extensions
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/txbrown/gqlgen-api-starter/internal/services"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+)
+
+// Factory builds an extension from the server's config and services, it
+// returns nil when the config turns the extension off
+type Factory func(cfg *utils.ServerConfig, services *services.Services) (graphql.HandlerExtension, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{}
+)
+
+// Register makes the extension available under the name, registering a name
+// twice panics
+func Register(name string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := factories[name]; ok {
+		panic("[Extensions.Register] extension registered twice: " + name)
+	}
+	factories[name] = f
+}
+
+// Names returns the names of the registered extensions
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return registered()
+}
+
+func registered() []string {
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Build returns the extensions with the names, in order. Unknown names are an
+// error, so a typo in the config doesn't silently turn a feature off
+func Build(names []string, cfg *utils.ServerConfig, services *services.Services) ([]graphql.HandlerExtension, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	result := []graphql.HandlerExtension{}
+	for _, name := range names {
+		f, ok := factories[name]
+		if !ok {
+			return nil, fmt.Errorf("[Extensions.Build] unknown extension: %s, known extensions: %v", name, registered())
+		}
+		ext, err := f(cfg, services)
+		if err != nil {
+			return nil, fmt.Errorf("[Extensions.Build] %s: %v", name, err)
+		}
+		if ext != nil {
+			result = append(result, ext)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,127 @@
+// Package federation serves the schema as a subgraph of an Apollo Federation
+// supergraph. The subgraph fields, _service and _entities, are generated in
+// the schema, the extension refuses them when federation is turned off so the
+// standalone endpoint serves the same graph as before
+package federation
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/gofrs/uuid"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/loaders"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/relay"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// The fields of the Query type the gateway queries the subgraph with
+const (
+	serviceField  = "_service"
+	entitiesField = "_entities"
+)
+
+var _ interface {
+	graphql.OperationContextMutator
+	graphql.FieldInterceptor
+	graphql.HandlerExtension
+} = Subgraph{}
+
+// Subgraph serves the subgraph fields when enabled, and batches the lookups
+// of the entities the gateway asks for
+type Subgraph struct {
+	Enabled bool
+}
+
+// ExtensionName the name of the extension
+func (s Subgraph) ExtensionName() string {
+	return "Federation"
+}
+
+// Validate the extension needs no schema
+func (s Subgraph) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+// MutateOperationContext refuses the subgraph fields when federation is off,
+// the way fields that don't exist are refused
+func (s Subgraph) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	if s.Enabled {
+		return nil
+	}
+	op := rc.Doc.Operations.ForName(rc.OperationName)
+	if op.Operation != ast.Query {
+		return nil
+	}
+	if f := subgraphField(op.SelectionSet); f != nil {
+		err := gqlerror.ErrorPosf(f.Position, `Cannot query field "%s" on type "Query".`, f.Name)
+		errcode.Set(err, errcode.ValidationFailed)
+		return err
+	}
+	return nil
+}
+
+func subgraphField(selectionSet ast.SelectionSet) *ast.Field {
+	for _, selection := range selectionSet {
+		switch s := selection.(type) {
+		case *ast.Field:
+			if s.Name == serviceField || s.Name == entitiesField {
+				return s
+			}
+		case *ast.InlineFragment:
+			if f := subgraphField(s.SelectionSet); f != nil {
+				return f
+			}
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				if f := subgraphField(s.Definition.SelectionSet); f != nil {
+					return f
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// InterceptField starts loading all the entities of the representations
+// before they are resolved one by one, so each type is fetched in one batch
+func (s Subgraph) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if !s.Enabled || fc == nil || fc.Object != "Query" || fc.Field.Name != entitiesField {
+		return next(ctx)
+	}
+	representations, _ := fc.Args["representations"].([]map[string]interface{})
+	if l := loaders.FromContext(ctx); l != nil {
+		userIDs, productIDs := keys(representations)
+		l.Prefetch(userIDs, productIDs)
+	}
+	return next(ctx)
+}
+
+// keys returns the keys of the users and the products of the representations,
+// the ones that aren't valid are left to their entity resolver to refuse
+func keys(representations []map[string]interface{}) (userIDs []string, productIDs []string) {
+	for _, rep := range representations {
+		typename, _ := rep["__typename"].(string)
+		id, ok := rep["id"].(string)
+		if !ok {
+			continue
+		}
+		key, err := relay.Key(id, typename)
+		if err != nil {
+			continue
+		}
+		// a key that isn't an id would fail the whole batch
+		if _, err := uuid.FromString(key); err != nil {
+			continue
+		}
+		switch typename {
+		case relay.TypeUser:
+			userIDs = append(userIDs, key)
+		case relay.TypeProduct:
+			productIDs = append(productIDs, key)
+		}
+	}
+	return userIDs, productIDs
+}
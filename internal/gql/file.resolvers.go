@@ -0,0 +1,78 @@
+package gql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/txbrown/gqlgen-api-starter/internal/audit"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/common"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/generated"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/transformations"
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
+)
+
+func (r *fileResolver) URL(ctx context.Context, obj *model.File) (string, error) {
+	return r.Services.FilesService.URL(obj.StorageKey, obj.Name)
+}
+
+func (r *fileResolver) Owner(ctx context.Context, obj *model.File) (*model.User, error) {
+	return loadUser(ctx, &obj.OwnerUserID)
+}
+
+func (r *mutationResolver) UploadFile(ctx context.Context, file graphql.Upload) (*model.File, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	dbo, err := r.Services.FilesService.Upload(ctx, cu, file.Filename, file.Size, file.File)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.File, err)
+	}
+
+	result := transformations.DBFileToGQLFile(dbo)
+	audit.SetTarget(ctx, consts.EntityNames.File, result.ID)
+	audit.SetChange(ctx, nil, result)
+	return result, nil
+}
+
+func (r *mutationResolver) DeleteFile(ctx context.Context, id string) (*model.File, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	audit.SetTarget(ctx, consts.EntityNames.File, id)
+	dbo, err := r.Services.FilesService.Delete(ctx, cu, id)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.File, err)
+	}
+
+	result := transformations.DBFileToGQLFile(dbo)
+	audit.SetChange(ctx, result, nil)
+	return result, nil
+}
+
+func (r *queryResolver) File(ctx context.Context, id string) (*model.File, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	dbo, err := r.Services.FilesService.FindById(cu, id)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.File, err)
+	}
+
+	return transformations.DBFileToGQLFile(dbo), nil
+}
+
+// File returns generated.FileResolver implementation.
+func (r *Resolver) File() generated.FileResolver { return &fileResolver{r} }
+
+type fileResolver struct{ *Resolver }
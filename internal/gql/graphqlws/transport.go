@@ -0,0 +1,348 @@
+// Package graphqlws serves subscriptions over the graphql-transport-ws
+// protocol, the successor of the graphql-ws (subscriptions-transport-ws)
+// protocol gqlgen's websocket transport speaks:
+// https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md
+package graphqlws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/gorilla/websocket"
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Subprotocol is the websocket subprotocol of graphql-transport-ws
+const Subprotocol = "graphql-transport-ws"
+
+const (
+	connectionInitMsg = "connection_init" // Client -> Server
+	connectionAckMsg  = "connection_ack"  // Server -> Client
+	pingMsg           = "ping"            // bidirectional
+	pongMsg           = "pong"            // bidirectional
+	subscribeMsg      = "subscribe"       // Client -> Server
+	nextMsg           = "next"            // Server -> Client
+	errorMsg          = "error"           // Server -> Client
+	completeMsg       = "complete"        // bidirectional
+)
+
+// Close codes of the protocol
+const (
+	closeInvalidMessage      = 4400
+	closeUnauthorized        = 4401
+	closeForbidden           = 4403
+	closeInitTimeout         = 4408
+	closeDuplicateID         = 4409
+	closeTooManyInitRequests = 4429
+)
+
+// Transport serves the websocket requests asking for the graphql-transport-ws
+// subprotocol, it must be added before gqlgen's websocket transport, which
+// takes every upgrade request
+type Transport struct {
+	Upgrader websocket.Upgrader
+	// InitFunc authenticates the connection from its connection_init payload
+	InitFunc transport.WebsocketInitFunc
+	// InitTimeout is how long the client has to send connection_init
+	InitTimeout time.Duration
+	// KeepAlivePingInterval is how often the server pings the client, 0 disables
+	// the pings
+	KeepAlivePingInterval time.Duration
+}
+
+var _ graphql.Transport = Transport{}
+
+type connection struct {
+	Transport
+	ctx         context.Context
+	conn        *websocket.Conn
+	exec        graphql.GraphExecutor
+	initPayload transport.InitPayload
+
+	mu     sync.Mutex
+	acked  bool
+	active map[string]context.CancelFunc
+}
+
+type message struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Supports the upgrade requests asking for graphql-transport-ws
+func (t Transport) Supports(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, p := range websocket.Subprotocols(r) {
+		if strings.EqualFold(p, Subprotocol) {
+			return true
+		}
+	}
+	return false
+}
+
+// Do upgrades the request and serves the connection until it is closed
+func (t Transport) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExecutor) {
+	ws, err := t.Upgrader.Upgrade(w, r, http.Header{
+		"Sec-Websocket-Protocol": []string{Subprotocol},
+	})
+	if err != nil {
+		logger.Warnf("[graphqlws.Do] unable to upgrade %T to websocket: %s", w, err.Error())
+		transport.SendErrorf(w, http.StatusBadRequest, "unable to upgrade")
+		return
+	}
+	if t.InitTimeout == 0 {
+		t.InitTimeout = 3 * time.Second
+	}
+
+	c := &connection{
+		Transport: t,
+		ctx:       r.Context(),
+		conn:      ws,
+		exec:      exec,
+		active:    map[string]context.CancelFunc{},
+	}
+	c.run()
+}
+
+func (c *connection) run() {
+	ctx, cancel := context.WithCancel(c.ctx)
+	defer func() {
+		cancel()
+		c.mu.Lock()
+		for _, closer := range c.active {
+			closer()
+		}
+		c.mu.Unlock()
+		c.conn.Close()
+	}()
+
+	timer := time.AfterFunc(c.InitTimeout, func() {
+		c.mu.Lock()
+		acked := c.acked
+		c.mu.Unlock()
+		if !acked {
+			c.close(closeInitTimeout, "Connection initialisation timeout")
+		}
+	})
+	defer timer.Stop()
+
+	if c.KeepAlivePingInterval > 0 {
+		go c.keepAlive(ctx)
+	}
+
+	for {
+		start := graphql.Now()
+		m, ok := c.read()
+		if !ok {
+			return
+		}
+
+		switch m.Type {
+		case connectionInitMsg:
+			if !c.init(m) {
+				return
+			}
+		case pingMsg:
+			c.write(&message{Type: pongMsg})
+		case pongMsg:
+		case subscribeMsg:
+			if !c.subscribe(start, m) {
+				return
+			}
+		case completeMsg:
+			c.mu.Lock()
+			closer := c.active[m.ID]
+			c.mu.Unlock()
+			if closer != nil {
+				closer()
+			}
+		default:
+			c.close(closeInvalidMessage, fmt.Sprintf("Unexpected message of type %s", m.Type))
+			return
+		}
+	}
+}
+
+func (c *connection) init(m *message) bool {
+	c.mu.Lock()
+	acked := c.acked
+	c.mu.Unlock()
+	if acked {
+		c.close(closeTooManyInitRequests, "Too many initialisation requests")
+		return false
+	}
+
+	if len(m.Payload) > 0 && !bytes.Equal(m.Payload, []byte("null")) {
+		c.initPayload = transport.InitPayload{}
+		if err := json.Unmarshal(m.Payload, &c.initPayload); err != nil {
+			c.close(closeInvalidMessage, "Invalid connection_init payload")
+			return false
+		}
+	}
+	if c.InitFunc != nil {
+		ctx, err := c.InitFunc(c.ctx, c.initPayload)
+		if err != nil {
+			c.close(closeForbidden, "Forbidden")
+			return false
+		}
+		c.ctx = ctx
+	}
+
+	c.mu.Lock()
+	c.acked = true
+	c.mu.Unlock()
+	c.write(&message{Type: connectionAckMsg})
+	return true
+}
+
+func (c *connection) subscribe(start time.Time, m *message) bool {
+	c.mu.Lock()
+	acked := c.acked
+	_, duplicate := c.active[m.ID]
+	c.mu.Unlock()
+	if !acked {
+		c.close(closeUnauthorized, "Unauthorized")
+		return false
+	}
+	if m.ID == "" {
+		c.close(closeInvalidMessage, "Subscribe message is missing its id")
+		return false
+	}
+	if duplicate {
+		c.close(closeDuplicateID, fmt.Sprintf("Subscriber for %s already exists", m.ID))
+		return false
+	}
+
+	var params *graphql.RawParams
+	if err := json.Unmarshal(m.Payload, &params); err != nil || params == nil {
+		c.close(closeInvalidMessage, "Invalid subscribe payload")
+		return false
+	}
+	params.ReadTime = graphql.TraceTiming{
+		Start: start,
+		End:   graphql.Now(),
+	}
+
+	ctx := graphql.StartOperationTrace(c.ctx)
+	rc, err := c.exec.CreateOperationContext(ctx, params)
+	if err != nil {
+		resp := c.exec.DispatchError(graphql.WithOperationContext(ctx, rc), err)
+		switch errcode.GetErrorKind(err) {
+		case errcode.KindProtocol:
+			c.sendError(m.ID, resp.Errors...)
+		default:
+			c.sendResponse(m.ID, &graphql.Response{Errors: err})
+			c.complete(m.ID)
+		}
+		return true
+	}
+
+	ctx = graphql.WithOperationContext(ctx, rc)
+	ctx, cancel := context.WithCancel(ctx)
+	c.mu.Lock()
+	c.active[m.ID] = cancel
+	c.mu.Unlock()
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err := rc.Recover(ctx, r)
+				c.sendError(m.ID, &gqlerror.Error{Message: err.Error()})
+			}
+			c.mu.Lock()
+			delete(c.active, m.ID)
+			c.mu.Unlock()
+			cancel()
+		}()
+
+		responses, ctx := c.exec.DispatchOperation(ctx, rc)
+		for {
+			response := responses(ctx)
+			if response == nil {
+				break
+			}
+			c.sendResponse(m.ID, response)
+		}
+		// the client already knows the subscriptions it completed itself
+		if ctx.Err() == nil {
+			c.complete(m.ID)
+		}
+	}()
+	return true
+}
+
+func (c *connection) keepAlive(ctx context.Context) {
+	ticker := time.NewTicker(c.KeepAlivePingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.write(&message{Type: pingMsg})
+		}
+	}
+}
+
+// read returns the next message, it closes the connection and returns false
+// if the client went away or sent an invalid message
+func (c *connection) read() (*message, bool) {
+	_, r, err := c.conn.NextReader()
+	if err != nil {
+		return nil, false
+	}
+	m := &message{}
+	if err := json.NewDecoder(r).Decode(m); err != nil || m.Type == "" {
+		c.close(closeInvalidMessage, "Invalid message received")
+		return nil, false
+	}
+	return m, true
+}
+
+func (c *connection) write(m *message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.conn.WriteJSON(m); err != nil {
+		logger.Debugf("[graphqlws.write] %s", err.Error())
+	}
+}
+
+func (c *connection) sendResponse(id string, response *graphql.Response) {
+	b, err := json.Marshal(response)
+	if err != nil {
+		panic(err)
+	}
+	c.write(&message{ID: id, Type: nextMsg, Payload: b})
+}
+
+func (c *connection) sendError(id string, errors ...*gqlerror.Error) {
+	b, err := json.Marshal(errors)
+	if err != nil {
+		panic(err)
+	}
+	c.write(&message{ID: id, Type: errorMsg, Payload: b})
+}
+
+func (c *connection) complete(id string) {
+	c.write(&message{ID: id, Type: completeMsg})
+}
+
+func (c *connection) close(code int, reason string) {
+	c.mu.Lock()
+	_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	c.mu.Unlock()
+	_ = c.conn.Close()
+}
@@ -0,0 +1,189 @@
+package gql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/audit"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/common"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/generated"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/relay"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/transformations"
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
+)
+
+func (r *groupResolver) Parent(ctx context.Context, obj *model.Group) (*model.Group, error) {
+	if obj.ParentID == nil {
+		return nil, nil
+	}
+	dbo, err := r.Services.GroupsService.FindById(getCurrentUser(ctx), *obj.ParentID)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Groups, err)
+	}
+	return transformations.DBGroupToGQLGroup(dbo), nil
+}
+
+func (r *mutationResolver) CreateGroup(ctx context.Context, input model.GroupInput) (*model.Group, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	dbo, err := r.Services.GroupsService.Create(cu, input)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Groups, err)
+	}
+	group := transformations.DBGroupToGQLGroup(dbo)
+	audit.SetTarget(ctx, consts.EntityNames.Groups, group.ID)
+	audit.SetChange(ctx, nil, group)
+	return group, nil
+}
+
+func (r *mutationResolver) UpdateGroup(ctx context.Context, id string, input model.GroupInput) (*model.Group, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	audit.SetTarget(ctx, consts.EntityNames.Groups, id)
+	before, err := r.Services.GroupsService.FindById(cu, id)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Groups, err)
+	}
+
+	dbo, err := r.Services.GroupsService.Update(cu, id, input)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Groups, err)
+	}
+	group := transformations.DBGroupToGQLGroup(dbo)
+	audit.SetChange(ctx, transformations.DBGroupToGQLGroup(before), group)
+	return group, nil
+}
+
+func (r *mutationResolver) DeleteGroup(ctx context.Context, id string) (bool, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return false, common.GqlUnauthorizedError(ctx)
+	}
+
+	audit.SetTarget(ctx, consts.EntityNames.Groups, id)
+	before, err := r.Services.GroupsService.FindById(cu, id)
+	if err != nil {
+		return false, logger.Errorfn(consts.EntityNames.Groups, err)
+	}
+
+	if err := r.Services.GroupsService.Delete(cu, id); err != nil {
+		return false, logger.Errorfn(consts.EntityNames.Groups, err)
+	}
+	audit.SetChange(ctx, transformations.DBGroupToGQLGroup(before), nil)
+	return true, nil
+}
+
+func (r *mutationResolver) AddGroupMember(ctx context.Context, groupID string, userID string) (*model.Group, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	userID, err := relay.Key(userID, relay.TypeUser)
+	if err != nil {
+		return nil, err
+	}
+
+	audit.SetEvent(ctx, audit.EventGroupMembership)
+	audit.SetTarget(ctx, consts.EntityNames.Groups, groupID)
+	before, err := r.Services.GroupsService.FindById(cu, groupID)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Groups, err)
+	}
+
+	dbo, err := r.Services.GroupsService.AddMember(cu, groupID, userID)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Groups, err)
+	}
+	audit.SetChange(ctx, groupMembers(before), groupMembers(dbo))
+	return transformations.DBGroupToGQLGroup(dbo), nil
+}
+
+func (r *mutationResolver) RemoveGroupMember(ctx context.Context, groupID string, userID string) (*model.Group, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	userID, err := relay.Key(userID, relay.TypeUser)
+	if err != nil {
+		return nil, err
+	}
+
+	audit.SetEvent(ctx, audit.EventGroupMembership)
+	audit.SetTarget(ctx, consts.EntityNames.Groups, groupID)
+	before, err := r.Services.GroupsService.FindById(cu, groupID)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Groups, err)
+	}
+
+	dbo, err := r.Services.GroupsService.RemoveMember(cu, groupID, userID)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Groups, err)
+	}
+	audit.SetChange(ctx, groupMembers(before), groupMembers(dbo))
+	return transformations.DBGroupToGQLGroup(dbo), nil
+}
+
+func (r *mutationResolver) SetGroupGrants(ctx context.Context, groupID string, roles []string, permissions []string) (*model.Group, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	audit.SetEvent(ctx, audit.EventRoleChange)
+	audit.SetTarget(ctx, consts.EntityNames.Groups, groupID)
+	before, err := r.Services.GroupsService.FindById(cu, groupID)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Groups, err)
+	}
+
+	dbo, err := r.Services.GroupsService.SetGrants(cu, groupID, roles, permissions)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Groups, err)
+	}
+	group := transformations.DBGroupToGQLGroup(dbo)
+	audit.SetChange(ctx, groupGrants(transformations.DBGroupToGQLGroup(before)), groupGrants(group))
+	return group, nil
+}
+
+func (r *queryResolver) Groups(ctx context.Context, id *string, limit *int, offset *int) (*model.Groups, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	dbRecords, more, err := r.Services.GroupsService.List(cu, id, limit, offset)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Groups, err)
+	}
+
+	record := &model.Groups{HasNextPage: more, List: []*model.Group{}}
+	for _, dbRec := range dbRecords {
+		record.List = append(record.List, transformations.DBGroupToGQLGroup(dbRec))
+	}
+	if isSelected(ctx, "count") {
+		count, err := r.Services.GroupsService.Count(cu, id)
+		if err != nil {
+			return nil, logger.Errorfn(consts.EntityNames.Groups, err)
+		}
+		total := int(count)
+		record.Count = &total
+	}
+	return record, nil
+}
+
+// Group returns generated.GroupResolver implementation.
+func (r *Resolver) Group() generated.GroupResolver { return &groupResolver{r} }
+
+type groupResolver struct{ *Resolver }
@@ -0,0 +1,81 @@
+package limits
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// costSchema computes the complexity of the fields annotated with @cost, the
+// rest is left to the generated schema
+type costSchema struct {
+	graphql.ExecutableSchema
+}
+
+// WithCosts returns the schema with the @cost annotations applied to the
+// complexity of its fields
+func WithCosts(es graphql.ExecutableSchema) graphql.ExecutableSchema {
+	return costSchema{ExecutableSchema: es}
+}
+
+// Complexity is the field's own complexity plus the complexity of its
+// selection, times the multiplier
+func (s costSchema) Complexity(typeName, field string, childComplexity int, args map[string]interface{}) (int, bool) {
+	def := s.Schema().Types[typeName]
+	if def == nil {
+		return s.ExecutableSchema.Complexity(typeName, field, childComplexity, args)
+	}
+	f := def.Fields.ForName(field)
+	if f == nil || f.Directives.ForName("cost") == nil {
+		return s.ExecutableSchema.Complexity(typeName, field, childComplexity, args)
+	}
+	cost := f.Directives.ForName("cost").ArgumentMap(nil)
+
+	complexity := 1
+	if v, ok := toInt(cost["complexity"]); ok {
+		complexity = v
+	}
+	multiplier := 1
+	if v, ok := toInt(cost["defaultMultiplier"]); ok {
+		multiplier = v
+	}
+	names, _ := cost["multipliers"].([]interface{})
+	for _, name := range names {
+		arg := args[name.(string)]
+		if v, ok := toInt(arg); ok {
+			// limits that aren't positive are refused by the lists, they
+			// are never charged less than the default
+			if v <= 0 && reflect.ValueOf(arg).Kind() != reflect.Slice {
+				break
+			}
+			multiplier = v
+			break
+		}
+	}
+
+	if multiplier > 0 && childComplexity > (math.MaxInt32-complexity)/multiplier {
+		return math.MaxInt32, true
+	}
+	return complexity + childComplexity*multiplier, true
+}
+
+// toInt reads an argument as an int, lists count as their length
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case nil:
+		return 0, false
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case json.Number:
+		i, err := n.Int64()
+		return int(i), err == nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+		return rv.Len(), true
+	}
+	return 0, false
+}
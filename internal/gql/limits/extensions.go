@@ -0,0 +1,123 @@
+package limits
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/99designs/gqlgen/complexity"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+var _ interface {
+	graphql.OperationContextMutator
+	graphql.HandlerExtension
+} = &Depth{}
+
+var _ interface {
+	graphql.OperationContextMutator
+	graphql.HandlerExtension
+} = &Complexity{}
+
+// Depth rejects the operations whose selections are nested deeper than the
+// limit, introspection fields are not counted
+type Depth struct {
+	Limit int
+}
+
+// ExtensionName the name of the extension
+func (d Depth) ExtensionName() string {
+	return "DepthLimit"
+}
+
+// Validate the extension needs no schema
+func (d Depth) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+// MutateOperationContext rejects the operation if it is too deep
+func (d Depth) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	if d.Limit <= 0 {
+		return nil
+	}
+	op := rc.Doc.Operations.ForName(rc.OperationName)
+	if depth := selectionDepth(op.SelectionSet); depth > d.Limit {
+		return &gqlerror.Error{
+			Message: fmt.Sprintf("operation has depth %d, which exceeds the limit of %d", depth, d.Limit),
+			Extensions: map[string]interface{}{
+				"code":  ErrCodeDepthLimit,
+				"depth": depth,
+				"limit": d.Limit,
+			},
+		}
+	}
+	return nil
+}
+
+func selectionDepth(selectionSet ast.SelectionSet) int {
+	depth := 0
+	for _, selection := range selectionSet {
+		d := 0
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name, "__") {
+				continue
+			}
+			d = 1 + selectionDepth(s.SelectionSet)
+		case *ast.InlineFragment:
+			d = selectionDepth(s.SelectionSet)
+		case *ast.FragmentSpread:
+			if s.Definition != nil {
+				d = selectionDepth(s.Definition.SelectionSet)
+			}
+		}
+		if d > depth {
+			depth = d
+		}
+	}
+	return depth
+}
+
+// Complexity rejects the operations that cost more than the request's budget,
+// the cost is computed from the @cost annotations of the schema
+type Complexity struct {
+	Budget func(ctx context.Context) int
+
+	es graphql.ExecutableSchema
+}
+
+// ExtensionName the name of the extension
+func (c Complexity) ExtensionName() string {
+	return "ComplexityLimit"
+}
+
+// Validate keeps the schema the cost is computed with
+func (c *Complexity) Validate(schema graphql.ExecutableSchema) error {
+	if c.Budget == nil {
+		return fmt.Errorf("complexity budget func can not be nil")
+	}
+	c.es = schema
+	return nil
+}
+
+// MutateOperationContext rejects the operation if it is over budget
+func (c Complexity) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	limit := c.Budget(ctx)
+	if limit <= 0 {
+		return nil
+	}
+	op := rc.Doc.Operations.ForName(rc.OperationName)
+	if cost := complexity.Calculate(c.es, op, rc.Variables); cost > limit {
+		return &gqlerror.Error{
+			Message: fmt.Sprintf("operation has complexity %d, which exceeds the limit of %d", cost, limit),
+			Extensions: map[string]interface{}{
+				"code":  ErrCodeComplexityLimit,
+				"cost":  cost,
+				"limit": limit,
+			},
+		}
+	}
+	return nil
+}
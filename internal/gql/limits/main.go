@@ -0,0 +1,32 @@
+// Package limits rejects operations that are too deep or too expensive before
+// they are executed, so a single nested query can't hammer the database
+package limits
+
+import (
+	"context"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+)
+
+const (
+	// ErrCodeDepthLimit is the error code of the operations nested too deep
+	ErrCodeDepthLimit = "DEPTH_LIMIT_EXCEEDED"
+	// ErrCodeComplexityLimit is the error code of the operations over budget
+	ErrCodeComplexityLimit = "COMPLEXITY_LIMIT_EXCEEDED"
+)
+
+// Budget returns the complexity budget of a request: the one of the API key it
+// was made with, if the key has its own, the admins' budget for platform
+// admins, or the default one
+func Budget(cfg utils.GQLLimitsConfig) func(ctx context.Context) int {
+	return func(ctx context.Context) int {
+		if key, ok := ctx.Value(utils.ProjectContextKeys.APIKeyCtxKey).(*models.UserAPIKey); ok && key.ComplexityLimit != nil {
+			return *key.ComplexityLimit
+		}
+		if cu, ok := ctx.Value(utils.ProjectContextKeys.UserCtxKey).(*models.User); ok && cu.IsPlatformAdmin() {
+			return cfg.AdminComplexityLimit
+		}
+		return cfg.ComplexityLimit
+	}
+}
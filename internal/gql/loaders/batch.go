@@ -0,0 +1,114 @@
+package loaders
+
+import (
+	"sync"
+	"time"
+)
+
+// fetchFunc loads the values of a batch of keys, keys with no value are left
+// out of the result
+type fetchFunc func(keys []interface{}) (map[interface{}]interface{}, error)
+
+// loader collects the keys requested during a short window, or until the batch
+// is full, and fetches them all at once. Results are cached for the lifetime
+// of the loader, which is a single operation
+type loader struct {
+	fetch    fetchFunc
+	wait     time.Duration
+	maxBatch int
+
+	mu    sync.Mutex
+	cache map[interface{}]*result
+	batch *batch
+}
+
+type result struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+type batch struct {
+	keys    []interface{}
+	results []*result
+	full    chan struct{}
+}
+
+func newLoader(fetch fetchFunc, wait time.Duration, maxBatch int) *loader {
+	return &loader{
+		fetch:    fetch,
+		wait:     wait,
+		maxBatch: maxBatch,
+		cache:    map[interface{}]*result{},
+	}
+}
+
+// load returns the value of the key, blocking until its batch is fetched
+func (l *loader) load(key interface{}) (interface{}, error) {
+	l.mu.Lock()
+	r, ok := l.cache[key]
+	if !ok {
+		r = &result{done: make(chan struct{})}
+		l.cache[key] = r
+		l.enqueue(key, r)
+	}
+	l.mu.Unlock()
+
+	<-r.done
+	return r.value, r.err
+}
+
+// prime starts loading the keys that aren't loaded yet, without waiting for
+// them, so keys loaded one after the other still share a batch
+func (l *loader) prime(keys ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		if _, ok := l.cache[key]; !ok {
+			r := &result{done: make(chan struct{})}
+			l.cache[key] = r
+			l.enqueue(key, r)
+		}
+	}
+}
+
+// enqueue adds the key to the open batch, starting a new one if needed. The
+// caller holds the lock
+func (l *loader) enqueue(key interface{}, r *result) {
+	if l.batch == nil {
+		l.batch = &batch{full: make(chan struct{})}
+		go l.dispatch(l.batch)
+	}
+	b := l.batch
+	b.keys = append(b.keys, key)
+	b.results = append(b.results, r)
+	if l.maxBatch > 0 && len(b.keys) >= l.maxBatch {
+		l.batch = nil
+		close(b.full)
+	}
+}
+
+// clear forgets the fetched values, the batches in flight are not affected
+func (l *loader) clear() {
+	l.mu.Lock()
+	l.cache = map[interface{}]*result{}
+	l.mu.Unlock()
+}
+
+func (l *loader) dispatch(b *batch) {
+	select {
+	case <-time.After(l.wait):
+		l.mu.Lock()
+		if l.batch == b {
+			l.batch = nil
+		}
+		l.mu.Unlock()
+	case <-b.full:
+	}
+
+	values, err := l.fetch(b.keys)
+	for i, key := range b.keys {
+		b.results[i].value, b.results[i].err = values[key], err
+		close(b.results[i].done)
+	}
+}
@@ -0,0 +1,185 @@
+package loaders
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/services"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+)
+
+const (
+	// batchWait is how long a batch waits for more keys before it is fetched
+	batchWait = 2 * time.Millisecond
+	// maxBatch bounds the number of keys fetched in a single query
+	maxBatch = 100
+)
+
+// Loaders batch the loading of the nested relations of a single operation, so
+// each level of the query issues one query instead of one per parent
+type Loaders struct {
+	users    *loader
+	profiles *loader
+	products *loader
+}
+
+// profilesKey profiles are paged per user, the users asking for the same
+// window are batched together
+type profilesKey struct {
+	UserID string
+	Limit  int
+	Offset int
+}
+
+type window struct {
+	Limit  int
+	Offset int
+}
+
+// New returns the loaders of an operation made by the current user
+func New(us services.UsersService, ps services.ProductsService, cu *models.User) *Loaders {
+	return &Loaders{
+		users:    newLoader(usersFetch(us, cu), batchWait, maxBatch),
+		profiles: newLoader(profilesFetch(us), batchWait, maxBatch),
+		products: newLoader(productsFetch(ps, cu), batchWait, maxBatch),
+	}
+}
+
+type contextKey struct{}
+
+// Middleware gives each operation its own loaders, for the user the operation
+// was authenticated as, their cache must not outlive it
+func Middleware(us services.UsersService, ps services.ProductsService) graphql.OperationMiddleware {
+	return func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+		cu, _ := ctx.Value(utils.ProjectContextKeys.UserCtxKey).(*models.User)
+		return next(NewContext(ctx, New(us, ps, cu)))
+	}
+}
+
+// NewContext returns a context carrying the operation's loaders
+func NewContext(ctx context.Context, l *Loaders) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the operation's loaders, if any
+func FromContext(ctx context.Context) *Loaders {
+	l, _ := ctx.Value(contextKey{}).(*Loaders)
+	return l
+}
+
+// Clear forgets the loaded values, subscriptions clear them before each event
+// so their nested relations are loaded fresh
+func (l *Loaders) Clear() {
+	l.users.clear()
+	l.profiles.clear()
+	l.products.clear()
+}
+
+// Prefetch starts loading the users and the products with the ids in a
+// single batch of each, ahead of resolvers that load them one by one
+func (l *Loaders) Prefetch(userIDs []string, productIDs []string) {
+	l.users.prime(keys(userIDs)...)
+	l.products.prime(keys(productIDs)...)
+}
+
+// User returns the user, nil if it doesn't exist or the current user can't
+// read it
+func (l *Loaders) User(id string) (*models.User, error) {
+	v, err := l.users.load(id)
+	if err != nil {
+		return nil, err
+	}
+	u, _ := v.(*models.User)
+	return u, nil
+}
+
+// Product returns the product, nil if it doesn't exist or the current user
+// can't read it
+func (l *Loaders) Product(id string) (*models.Product, error) {
+	v, err := l.products.load(id)
+	if err != nil {
+		return nil, err
+	}
+	p, _ := v.(*models.Product)
+	return p, nil
+}
+
+// Profiles returns the window of the user's profiles
+func (l *Loaders) Profiles(userID string, limit int, offset int) ([]*models.UserProfile, error) {
+	v, err := l.profiles.load(profilesKey{UserID: userID, Limit: limit, Offset: offset})
+	if err != nil {
+		return nil, err
+	}
+	profiles, _ := v.([]*models.UserProfile)
+	return profiles, nil
+}
+
+func usersFetch(us services.UsersService, cu *models.User) fetchFunc {
+	return func(keys []interface{}) (map[interface{}]interface{}, error) {
+		ids := make([]string, len(keys))
+		for i, k := range keys {
+			ids[i] = k.(string)
+		}
+		dbRecords, err := us.FindByIds(cu, ids)
+		if err != nil {
+			return nil, err
+		}
+		values := map[interface{}]interface{}{}
+		for _, dbo := range dbRecords {
+			values[dbo.ID.String()] = dbo
+		}
+		return values, nil
+	}
+}
+
+func productsFetch(ps services.ProductsService, cu *models.User) fetchFunc {
+	return func(keys []interface{}) (map[interface{}]interface{}, error) {
+		ids := make([]string, len(keys))
+		for i, k := range keys {
+			ids[i] = k.(string)
+		}
+		dbRecords, err := ps.FindByIds(cu, ids)
+		if err != nil {
+			return nil, err
+		}
+		values := map[interface{}]interface{}{}
+		for _, dbo := range dbRecords {
+			values[dbo.ID.String()] = dbo
+		}
+		return values, nil
+	}
+}
+
+func keys(ids []string) []interface{} {
+	result := make([]interface{}, len(ids))
+	for i, id := range ids {
+		result[i] = id
+	}
+	return result
+}
+
+func profilesFetch(us services.UsersService) fetchFunc {
+	return func(keys []interface{}) (map[interface{}]interface{}, error) {
+		userIDs := map[window][]string{}
+		for _, k := range keys {
+			pk := k.(profilesKey)
+			w := window{Limit: pk.Limit, Offset: pk.Offset}
+			userIDs[w] = append(userIDs[w], pk.UserID)
+		}
+		values := map[interface{}]interface{}{}
+		for w, ids := range userIDs {
+			dbRecords, err := us.Profiles(ids, w.Limit, w.Offset)
+			if err != nil {
+				return nil, err
+			}
+			for _, dbo := range dbRecords {
+				k := profilesKey{UserID: dbo.UserID.String(), Limit: w.Limit, Offset: w.Offset}
+				profiles, _ := values[k].([]*models.UserProfile)
+				values[k] = append(profiles, dbo)
+			}
+		}
+		return values, nil
+	}
+}
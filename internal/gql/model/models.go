@@ -0,0 +1,104 @@
+package model
+
+import "time"
+
+// Models declared here are picked up by gqlgen's autobind instead of being
+// generated, so they can carry data that is not exposed in the schema.
+
+// User is the gql type of a user, its profiles and the users that created and
+// last updated it are resolved on demand, batched per request
+type User struct {
+	ID          string     `json:"id"`
+	CreatedByID *string    `json:"-"`
+	UpdatedByID *string    `json:"-"`
+	Email       *string    `json:"email"`
+	AvatarURL   *string    `json:"avatarURL"`
+	Name        *string    `json:"name"`
+	FirstName   *string    `json:"firstName"`
+	LastName    *string    `json:"lastName"`
+	NickName    *string    `json:"nickName"`
+	Description *string    `json:"description"`
+	Location    *string    `json:"location"`
+	APIkey      *string    `json:"APIkey"`
+	Roles       []string   `json:"roles"`
+	Permissions []string   `json:"permissions"`
+	CreatedAt   *time.Time `json:"createdAt"`
+	UpdatedAt   *time.Time `json:"updatedAt"`
+	Token       *string    `json:"token"`
+}
+
+// UserProfile is the gql type of an OAuth/DB profile, it keeps the id of the
+// user it belongs to so the profile's owner can be resolved
+type UserProfile struct {
+	ID             int        `json:"id"`
+	UserID         string     `json:"-"`
+	CreatedByID    *string    `json:"-"`
+	UpdatedByID    *string    `json:"-"`
+	Email          string     `json:"email"`
+	ExternalUserID *string    `json:"externalUserId"`
+	AvatarURL      *string    `json:"avatarURL"`
+	Name           *string    `json:"name"`
+	FirstName      *string    `json:"firstName"`
+	LastName       *string    `json:"lastName"`
+	NickName       *string    `json:"nickName"`
+	Description    *string    `json:"description"`
+	Location       *string    `json:"location"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      *time.Time `json:"updatedAt"`
+}
+
+// IsNode users are refetched by their global id
+func (User) IsNode() {}
+
+// IsEntity users are owned by this subgraph of the federated graph
+func (User) IsEntity() {}
+
+// IsNode profiles are refetched by their global id
+func (UserProfile) IsNode() {}
+
+// Owned is implemented by the gql types that belong to a user
+type Owned interface {
+	OwnerID() string
+}
+
+// OwnerID a user owns itself
+func (u *User) OwnerID() string {
+	return u.ID
+}
+
+// OwnerID returns the id of the user the profile belongs to
+func (p *UserProfile) OwnerID() string {
+	return p.UserID
+}
+
+// OwnerID returns the id of the user that uploaded the file
+func (f *File) OwnerID() string {
+	return f.OwnerUserID
+}
+
+// Group is the gql type of a group, its parent is resolved on demand so the
+// whole chain of ancestors can be queried
+type Group struct {
+	ID          string     `json:"id"`
+	ParentID    *string    `json:"-"`
+	Name        string     `json:"name"`
+	Description *string    `json:"description"`
+	Roles       []string   `json:"roles"`
+	Permissions []string   `json:"permissions"`
+	Members     []*User    `json:"members"`
+	CreatedAt   *time.Time `json:"createdAt"`
+	UpdatedAt   *time.Time `json:"updatedAt"`
+}
+
+// File is the gql type of an uploaded file, its download URL is signed on
+// demand from the storage key
+type File struct {
+	ID          string     `json:"id"`
+	OwnerUserID string     `json:"-"`
+	StorageKey  string     `json:"-"`
+	Name        string     `json:"name"`
+	ContentType string     `json:"contentType"`
+	Size        int64      `json:"size"`
+	Checksum    string     `json:"checksum"`
+	CreatedAt   *time.Time `json:"createdAt"`
+}
@@ -0,0 +1,122 @@
+package gql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/audit"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/common"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/relay"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/transformations"
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
+	"gorm.io/gorm"
+)
+
+func (r *mutationResolver) CreateOrganization(ctx context.Context, input model.OrganizationInput) (*model.Organization, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	dbo, err := r.Services.OrganizationsService.Create(cu, input)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Organizations, err)
+	}
+	org := transformations.DBOrganizationToGQLOrganization(dbo)
+	audit.SetTarget(ctx, consts.EntityNames.Organizations, org.ID)
+	audit.SetChange(ctx, nil, org)
+	return org, nil
+}
+
+func (r *mutationResolver) SetOrganizationMember(ctx context.Context, organizationID string, userID string, roles []string) (*model.OrganizationMember, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	userID, err := relay.Key(userID, relay.TypeUser)
+	if err != nil {
+		return nil, err
+	}
+
+	audit.SetEvent(ctx, audit.EventRoleChange)
+	audit.SetTarget(ctx, consts.EntityNames.OrgMembers, organizationID+"/"+userID)
+	before, err := r.Services.OrganizationsService.FindMember(cu, organizationID, userID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, logger.Errorfn(consts.EntityNames.OrgMembers, err)
+	}
+
+	dbo, err := r.Services.OrganizationsService.SaveMember(cu, organizationID, userID, roles)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.OrgMembers, err)
+	}
+	member := transformations.DBOrganizationMemberToGQLOrganizationMember(dbo)
+	audit.SetChange(ctx, memberRoles(transformations.DBOrganizationMemberToGQLOrganizationMember(before)), memberRoles(member))
+	return member, nil
+}
+
+func (r *mutationResolver) RemoveOrganizationMember(ctx context.Context, organizationID string, userID string) (bool, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return false, common.GqlUnauthorizedError(ctx)
+	}
+
+	userID, err := relay.Key(userID, relay.TypeUser)
+	if err != nil {
+		return false, err
+	}
+
+	audit.SetEvent(ctx, audit.EventRoleChange)
+	audit.SetTarget(ctx, consts.EntityNames.OrgMembers, organizationID+"/"+userID)
+	before, err := r.Services.OrganizationsService.FindMember(cu, organizationID, userID)
+	if err != nil {
+		return false, logger.Errorfn(consts.EntityNames.OrgMembers, err)
+	}
+
+	if err := r.Services.OrganizationsService.RemoveMember(cu, organizationID, userID); err != nil {
+		return false, logger.Errorfn(consts.EntityNames.OrgMembers, err)
+	}
+	audit.SetChange(ctx, memberRoles(transformations.DBOrganizationMemberToGQLOrganizationMember(before)), nil)
+	return true, nil
+}
+
+func (r *mutationResolver) SwitchOrganization(ctx context.Context, id string) (*model.SignInResponse, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	if err := r.Services.OrganizationsService.Activate(cu, id); err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Organizations, err)
+	}
+	token, err := r.Services.UsersService.IssueToken(cu, r.Config)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Organizations, err)
+	}
+	return &model.SignInResponse{
+		Token: token,
+		User:  transformations.DBUserToGQLUser(cu),
+	}, nil
+}
+
+func (r *queryResolver) Organizations(ctx context.Context) ([]*model.Organization, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	dbRecords, err := r.Services.OrganizationsService.ForUser(cu)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Organizations, err)
+	}
+
+	results := []*model.Organization{}
+	for _, dbRec := range dbRecords {
+		results = append(results, transformations.DBOrganizationToGQLOrganization(dbRec))
+	}
+	return results, nil
+}
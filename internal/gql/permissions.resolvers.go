@@ -0,0 +1,32 @@
+package gql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+
+import (
+	"context"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
+)
+
+func (r *queryResolver) Can(ctx context.Context, action string, entity string, id *string) (bool, error) {
+	cu := getCurrentUser(ctx)
+
+	result, err := r.Services.AccessService.Can(cu, model.PermissionCheckInput{Action: action, Entity: entity, ID: id})
+	if err != nil {
+		return false, logger.Errorfn(consts.EntityNames.Permissions, err)
+	}
+	return result.Allowed, nil
+}
+
+func (r *queryResolver) CanAll(ctx context.Context, checks []*model.PermissionCheckInput) ([]*model.PermissionCheck, error) {
+	cu := getCurrentUser(ctx)
+
+	results, err := r.Services.AccessService.CanAll(cu, checks)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Permissions, err)
+	}
+	return results, nil
+}
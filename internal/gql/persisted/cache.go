@@ -0,0 +1,73 @@
+// Package persisted serves GraphQL documents by the sha256 hash of their text:
+// the automatic persisted queries of the Apollo clients and the trusted
+// documents imported from the manifests of the client builds
+package persisted
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/internal/services"
+)
+
+// Cache keeps the automatic persisted queries in memory, optionally backed by
+// the database so the queries registered on an instance are known by all
+type Cache struct {
+	lru   *lru.LRU
+	store services.PersistedQueriesService
+}
+
+var _ graphql.Cache = &Cache{}
+
+// NewCache returns a cache of the size, store can be nil to keep the queries
+// in memory only
+func NewCache(size int, store services.PersistedQueriesService) *Cache {
+	return &Cache{
+		lru:   lru.New(size),
+		store: store,
+	}
+}
+
+// Get looks the query up in memory, then in the store
+func (c *Cache) Get(ctx context.Context, hash string) (interface{}, bool) {
+	if query, ok := c.lru.Get(ctx, hash); ok {
+		return query, true
+	}
+	if c.store == nil {
+		return nil, false
+	}
+	query, ok, err := c.store.Find(hash)
+	if err != nil {
+		logger.Errorf("[persisted.Cache] %s: %v", hash, err)
+		return nil, false
+	}
+	if ok {
+		c.lru.Add(ctx, hash, query)
+	}
+	return query, ok
+}
+
+// Add registers the query, the store is only written when the query isn't
+// known by the instance yet
+func (c *Cache) Add(ctx context.Context, hash string, query interface{}) {
+	if _, ok := c.lru.Get(ctx, hash); ok {
+		return
+	}
+	c.lru.Add(ctx, hash, query)
+	if c.store == nil {
+		return
+	}
+	if err := c.store.Register(hash, query.(string)); err != nil {
+		logger.Errorf("[persisted.Cache] %s: %v", hash, err)
+	}
+}
+
+// Hash returns the hash a document is persisted with
+func Hash(query string) string {
+	b := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(b[:])
+}
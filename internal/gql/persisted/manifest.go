@@ -0,0 +1,82 @@
+package persisted
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+)
+
+// ApolloManifestFormat is the format of the manifests generated by Apollo's
+// persisted query tooling
+const ApolloManifestFormat = "apollo-persisted-query-manifest"
+
+type apolloManifest struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	Operations []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+		Body string `json:"body"`
+	} `json:"operations"`
+}
+
+// ParseManifest reads the operations of a client build's manifest, either an
+// Apollo persisted query manifest or a flat object of hashes to documents, as
+// generated by Relay or persistgraphql. The hashes must be the sha256 of the
+// documents
+func ParseManifest(r io.Reader, client string) ([]*models.PersistedQuery, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	queries := []*models.PersistedQuery{}
+	add := func(hash string, name string, body string) error {
+		if hash != Hash(body) {
+			return fmt.Errorf("manifest operation %q: hash %s is not the sha256 of its document", name, hash)
+		}
+		q := &models.PersistedQuery{Hash: hash, Query: body}
+		if name != "" {
+			q.Name = &name
+		}
+		if client != "" {
+			q.Client = &client
+		}
+		queries = append(queries, q)
+		return nil
+	}
+
+	if _, ok := raw["format"]; ok {
+		m := apolloManifest{}
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, err
+		}
+		if m.Format != ApolloManifestFormat || m.Version != 1 {
+			return nil, fmt.Errorf("unsupported manifest format %s, version %d", m.Format, m.Version)
+		}
+		for _, op := range m.Operations {
+			if err := add(op.ID, op.Name, op.Body); err != nil {
+				return nil, err
+			}
+		}
+		return queries, nil
+	}
+
+	for hash, v := range raw {
+		var body string
+		if err := json.Unmarshal(v, &body); err != nil {
+			return nil, fmt.Errorf("manifest operation %s: %v", hash, err)
+		}
+		if err := add(hash, "", body); err != nil {
+			return nil, err
+		}
+	}
+	return queries, nil
+}
@@ -0,0 +1,94 @@
+package persisted
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/internal/services"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// ErrCodeNotTrusted is the error code of the operations that were not
+// registered ahead of time
+const ErrCodeNotTrusted = "OPERATION_NOT_TRUSTED"
+
+var _ interface {
+	graphql.OperationParameterMutator
+	graphql.HandlerExtension
+} = &Trusted{}
+
+// Trusted only executes the documents imported from the manifests of the
+// client builds. Clients send either the hash of the document, as with
+// automatic persisted queries, or the document itself, clients can't register
+// new ones
+type Trusted struct {
+	store services.PersistedQueriesService
+	lru   *lru.LRU
+}
+
+// NewTrusted returns the extension, the trusted documents found in the store
+// are cached in memory
+func NewTrusted(size int, store services.PersistedQueriesService) *Trusted {
+	return &Trusted{
+		store: store,
+		lru:   lru.New(size),
+	}
+}
+
+// ExtensionName the name of the extension
+func (t Trusted) ExtensionName() string {
+	return "TrustedDocuments"
+}
+
+// Validate the extension needs the store
+func (t Trusted) Validate(schema graphql.ExecutableSchema) error {
+	if t.store == nil {
+		return fmt.Errorf("trusted documents store can not be nil")
+	}
+	return nil
+}
+
+// MutateOperationParameters replaces the hash with the trusted document, or
+// rejects the operation
+func (t Trusted) MutateOperationParameters(ctx context.Context, rawParams *graphql.RawParams) *gqlerror.Error {
+	hash := ""
+	if rawParams.Query != "" {
+		hash = Hash(rawParams.Query)
+	} else if ext, ok := rawParams.Extensions["persistedQuery"].(map[string]interface{}); ok {
+		hash, _ = ext["sha256Hash"].(string)
+	}
+
+	query, ok := t.lookup(ctx, hash)
+	if !ok {
+		return &gqlerror.Error{
+			Message: "operation is not a trusted document",
+			Extensions: map[string]interface{}{
+				"code": ErrCodeNotTrusted,
+				"hash": hash,
+			},
+		}
+	}
+	rawParams.Query = query
+	return nil
+}
+
+func (t Trusted) lookup(ctx context.Context, hash string) (string, bool) {
+	if hash == "" {
+		return "", false
+	}
+	if query, ok := t.lru.Get(ctx, hash); ok {
+		return query.(string), true
+	}
+	query, ok, err := t.store.FindTrusted(hash)
+	if err != nil {
+		logger.Errorf("[persisted.Trusted] %s: %v", hash, err)
+		return "", false
+	}
+	if ok {
+		t.lru.Add(ctx, hash, query)
+	}
+	return query, ok
+}
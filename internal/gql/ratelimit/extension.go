@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/complexity"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// extensionName is the key of the bucket's state in the response extensions
+const extensionName = "rateLimit"
+
+var _ interface {
+	graphql.OperationContextMutator
+	graphql.ResponseInterceptor
+	graphql.HandlerExtension
+} = &Limiter{}
+
+// Limiter charges the operations their complexity, as computed for the
+// complexity limit, and rejects them when the principal's bucket runs out
+type Limiter struct {
+	Store Store
+	Tiers Tiers
+	// FailOpen lets the operations through when the store fails, otherwise
+	// they are refused
+	FailOpen bool
+
+	es graphql.ExecutableSchema
+}
+
+// ExtensionName the name of the extension
+func (l Limiter) ExtensionName() string {
+	return "RateLimit"
+}
+
+// Validate keeps the schema the cost is computed with
+func (l *Limiter) Validate(schema graphql.ExecutableSchema) error {
+	if l.Store == nil {
+		return fmt.Errorf("rate limit store can not be nil")
+	}
+	l.es = schema
+	return nil
+}
+
+// MutateOperationContext charges the operation, rejecting it when the bucket
+// doesn't hold its cost or, unless failing open, when the store fails
+func (l Limiter) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	op := rc.Doc.Operations.ForName(rc.OperationName)
+	cost := complexity.Calculate(l.es, op, rc.Variables)
+	key, tier := l.Tiers.principal(ctx)
+
+	result, err := l.Store.Take(ctx, key, cost, tier)
+	if err != nil {
+		logger.Errorf("[RateLimit] %s: %v", key, err)
+		if l.FailOpen {
+			return nil
+		}
+		return gqlerror.Errorf("rate limit could not be checked")
+	}
+	rc.Stats.SetExtension(extensionName, result)
+	setHeaders(ctx, result)
+	if result.Allowed {
+		return nil
+	}
+
+	message := fmt.Sprintf("operation has complexity %d, which exceeds the %d remaining of the rate limit", cost, result.Remaining)
+	if cost > result.Limit {
+		message = fmt.Sprintf("operation has complexity %d, which exceeds the rate limit of %d", cost, result.Limit)
+	}
+	return &gqlerror.Error{
+		Message: message,
+		Extensions: map[string]interface{}{
+			"code":       ErrCodeRateLimited,
+			"cost":       cost,
+			"limit":      result.Limit,
+			"remaining":  result.Remaining,
+			"retryAfter": seconds(result.RetryAfter),
+		},
+	}
+}
+
+// InterceptResponse reports the state of the bucket in the response
+func (l Limiter) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	if graphql.HasOperationContext(ctx) {
+		if result, ok := graphql.GetOperationContext(ctx).Stats.GetExtension(extensionName).(Result); ok {
+			graphql.RegisterExtension(ctx, extensionName, map[string]interface{}{
+				"limit":     result.Limit,
+				"remaining": result.Remaining,
+				"reset":     seconds(result.Reset),
+			})
+		}
+	}
+	return next(ctx)
+}
+
+// setHeaders reports the state of the bucket in the headers of the HTTP
+// response, the websocket messages have none
+func setHeaders(ctx context.Context, result Result) {
+	h, ok := ctx.Value(utils.ProjectContextKeys.ResponseHeaderCtxKey).(http.Header)
+	if !ok {
+		return
+	}
+	h.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	h.Set("X-RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+	if !result.Allowed {
+		h.Set("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+	}
+}
+
+// seconds rounds the duration up to whole seconds
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
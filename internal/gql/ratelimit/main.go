@@ -0,0 +1,108 @@
+// Package ratelimit charges each operation its complexity against a token
+// bucket of the principal making it: the API key, the user or the client's IP.
+// The size and refill rate of the buckets depend on the principal's roles
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+)
+
+const (
+	// ErrCodeRateLimited is the error code of the operations over the rate
+	// limit
+	ErrCodeRateLimited = "RATE_LIMITED"
+	// TierAnonymous is the tier of the unauthenticated clients
+	TierAnonymous = "anonymous"
+	// TierDefault is the tier of the users none of whose roles has a tier
+	TierDefault = "default"
+)
+
+// Tier is the bucket of a kind of principal: up to Capacity of complexity can
+// be spent at once, and it refills by RefillPerMinute
+type Tier struct {
+	Capacity        int
+	RefillPerMinute int
+}
+
+// Tiers are the tiers by role name, with the anonymous and default ones
+type Tiers map[string]Tier
+
+// ParseTiers reads the tiers of the config, role:capacity:refillPerMinute
+func ParseTiers(entries []string) (Tiers, error) {
+	tiers := Tiers{}
+	for _, entry := range entries {
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid tier %q, expected role:capacity:refillPerMinute", entry)
+		}
+		capacity, err := strconv.Atoi(parts[1])
+		if err != nil || capacity <= 0 {
+			return nil, fmt.Errorf("invalid capacity of tier %q", entry)
+		}
+		refill, err := strconv.Atoi(parts[2])
+		if err != nil || refill <= 0 {
+			return nil, fmt.Errorf("invalid refill of tier %q", entry)
+		}
+		tiers[parts[0]] = Tier{Capacity: capacity, RefillPerMinute: refill}
+	}
+	for _, name := range []string{TierAnonymous, TierDefault} {
+		if _, ok := tiers[name]; !ok {
+			return nil, fmt.Errorf("missing the %s tier", name)
+		}
+	}
+	return tiers, nil
+}
+
+// For returns the tier of the user, the largest of their roles' tiers
+func (t Tiers) For(u *models.User) Tier {
+	if u == nil {
+		return t[TierAnonymous]
+	}
+	tier, found := t[TierDefault], false
+	for _, r := range u.Roles {
+		if rt, ok := t[r.Name]; ok && (!found || rt.Capacity > tier.Capacity) {
+			tier, found = rt, true
+		}
+	}
+	return tier
+}
+
+// principal returns the bucket key and the tier of the request, the API key
+// it was made with, its user or its client's IP
+func (t Tiers) principal(ctx context.Context) (string, Tier) {
+	cu, _ := ctx.Value(utils.ProjectContextKeys.UserCtxKey).(*models.User)
+	if key, ok := ctx.Value(utils.ProjectContextKeys.APIKeyCtxKey).(*models.UserAPIKey); ok {
+		return "key:" + strconv.Itoa(key.ID), t.For(cu)
+	}
+	if cu != nil {
+		return "user:" + cu.ID.String(), t.For(cu)
+	}
+	ip, _ := ctx.Value(utils.ProjectContextKeys.ClientIPCtxKey).(string)
+	return "ip:" + ip, t[TierAnonymous]
+}
+
+// Result is the state of a bucket after an operation was charged to it
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until the operation can be charged, when it
+	// wasn't allowed
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+}
+
+// Store keeps the buckets, a shared store lets the servers enforce a single
+// limit per principal
+type Store interface {
+	// Take charges the cost to the bucket of the key if it has enough tokens
+	Take(ctx context.Context, key string, cost int, tier Tier) (Result, error)
+}
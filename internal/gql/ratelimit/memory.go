@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often the full buckets are dropped from memory, a full
+// bucket is the same as no bucket
+const pruneInterval = time.Minute
+
+var _ Store = &MemoryStore{}
+
+// MemoryStore keeps the buckets in the server's memory, each server enforces
+// its own limit
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	pruned  time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	tier    Tier
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}}
+}
+
+// Take charges the cost to the bucket of the key if it has enough tokens
+func (s *MemoryStore) Take(ctx context.Context, key string, cost int, tier Tier) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.prune(now)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(tier.Capacity), updated: now, tier: tier}
+		s.buckets[key] = b
+	}
+	b.refill(now)
+	// the tier changes with the principal's roles
+	b.tier = tier
+	b.tokens = math.Min(b.tokens, float64(tier.Capacity))
+
+	result := Result{Limit: tier.Capacity}
+	if float64(cost) <= b.tokens {
+		b.tokens -= float64(cost)
+		result.Allowed = true
+	} else if cost <= tier.Capacity {
+		result.RetryAfter = b.until(float64(cost))
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = b.until(float64(tier.Capacity))
+	return result, nil
+}
+
+// refill adds the tokens earned since the last update
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updated).Minutes()
+	b.tokens = math.Min(float64(b.tier.Capacity), b.tokens+elapsed*float64(b.tier.RefillPerMinute))
+	b.updated = now
+}
+
+// until returns how long until the bucket holds the tokens
+func (b *bucket) until(tokens float64) time.Duration {
+	if b.tokens >= tokens {
+		return 0
+	}
+	minutes := (tokens - b.tokens) / float64(b.tier.RefillPerMinute)
+	return time.Duration(math.Ceil(minutes * float64(time.Minute)))
+}
+
+// prune drops the buckets that have refilled, the caller holds the lock
+func (s *MemoryStore) prune(now time.Time) {
+	if now.Sub(s.pruned) < pruneInterval {
+		return
+	}
+	s.pruned = now
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.tier.Capacity) {
+			delete(s.buckets, key)
+		}
+	}
+}
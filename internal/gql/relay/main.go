@@ -0,0 +1,58 @@
+// Package relay implements the Relay global object ids: opaque ids encoding
+// the type of the node and its key, unique across all the types
+package relay
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Types of the nodes, by their GraphQL name
+const (
+	TypeUser        = "User"
+	TypeUserProfile = "UserProfile"
+	TypeProduct     = "Product"
+)
+
+// ErrInvalidGlobalID the id is not a global id of the expected type
+var ErrInvalidGlobalID = errors.New("invalid global id")
+
+var types = map[string]bool{
+	TypeUser:        true,
+	TypeUserProfile: true,
+	TypeProduct:     true,
+}
+
+// ToGlobalID returns the global id of the node of the type with the key
+func ToGlobalID(typename string, key string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(typename + ":" + key))
+}
+
+// FromGlobalID returns the type and the key of a global id
+func FromGlobalID(id string) (string, string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(id)
+	if err != nil {
+		return "", "", ErrInvalidGlobalID
+	}
+	parts := strings.SplitN(string(b), ":", 2)
+	if len(parts) != 2 || !types[parts[0]] || parts[1] == "" {
+		return "", "", ErrInvalidGlobalID
+	}
+	return parts[0], parts[1], nil
+}
+
+// Key returns the key of an id given for a node of the type, any type when
+// empty. Ids that are not global ids are the keys themselves, as issued
+// before the global ids
+func Key(id string, typename string) (string, error) {
+	t, key, err := FromGlobalID(id)
+	if err != nil {
+		return id, nil
+	}
+	if typename != "" && t != typename {
+		return "", fmt.Errorf("%w: %s is not a %s id", ErrInvalidGlobalID, id, typename)
+	}
+	return key, nil
+}
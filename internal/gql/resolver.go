@@ -4,11 +4,20 @@ package gql
 
 import (
 	"context"
+	"strconv"
 
+	"github.com/99designs/gqlgen/graphql"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/common"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/loaders"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/relay"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/transformations"
 	"github.com/txbrown/gqlgen-api-starter/internal/logger"
 	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
 	"github.com/txbrown/gqlgen-api-starter/internal/services"
 	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
 )
 
 // This file will not be regenerated automatically.
@@ -16,11 +25,148 @@ import (
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct {
+	Config   *utils.ServerConfig
 	Services *services.Services
 }
 
 func getCurrentUser(ctx context.Context) *models.User {
-	cu := ctx.Value(utils.ProjectContextKeys.UserCtxKey).(*models.User)
+	cu, ok := ctx.Value(utils.ProjectContextKeys.UserCtxKey).(*models.User)
+	if !ok || cu == nil {
+		return nil
+	}
 	logger.Infof("currentUser: %s - %s", cu.Email, cu.ID)
 	return cu
 }
+
+// isSelected reports whether the client selected the field on the object
+// being resolved, so work nobody asked for, e.g. counts, can be skipped
+func isSelected(ctx context.Context, name string) bool {
+	for _, f := range graphql.CollectFieldsCtx(ctx, nil) {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// loadUser resolves a user relation through the request's loader, so the
+// relation is fetched once for all the parents of the same level
+func loadUser(ctx context.Context, id *string) (*model.User, error) {
+	if id == nil {
+		return nil, nil
+	}
+	dbo, err := loaders.FromContext(ctx).User(*id)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Users, err)
+	}
+	return transformations.DBUserToGQLUser(dbo), nil
+}
+
+// valueOr returns the value of an optional argument, the fallback when it's
+// null
+func valueOr(v *string, fallback string) string {
+	if v == nil {
+		return fallback
+	}
+	return *v
+}
+
+// intOr returns the value of an optional argument, the fallback when it's null
+func intOr(v *int, fallback int) int {
+	if v == nil {
+		return fallback
+	}
+	return *v
+}
+
+// nodeKey returns the key of the optional id given for a node of the type
+func nodeKey(id *string, typename string) (*string, error) {
+	if id == nil {
+		return nil, nil
+	}
+	key, err := relay.Key(*id, typename)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// node returns the object with the global id, through the service of its
+// type so the type's access rules apply
+func (r *queryResolver) node(cu *models.User, id string) (model.Node, error) {
+	typename, key, err := relay.FromGlobalID(id)
+	if err != nil {
+		return nil, err
+	}
+	switch typename {
+	case relay.TypeUser:
+		dbo, err := r.Services.UsersService.FindById(cu, key)
+		if err != nil {
+			return nil, logger.Errorfn(consts.EntityNames.Users, err)
+		}
+		return transformations.DBUserToGQLUser(dbo), nil
+	case relay.TypeUserProfile:
+		profileID, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, relay.ErrInvalidGlobalID
+		}
+		dbo, err := r.Services.UsersService.FindProfileById(cu, profileID)
+		if err != nil {
+			return nil, logger.Errorfn(consts.EntityNames.UserProfiles, err)
+		}
+		return transformations.DBUserProfileToGQLUserProfile(dbo), nil
+	case relay.TypeProduct:
+		dbo, err := r.Services.ProductsService.FindById(cu, key)
+		if err != nil {
+			return nil, err
+		}
+		return transformations.DBProductToGQLProduct(dbo), nil
+	}
+	return nil, relay.ErrInvalidGlobalID
+}
+
+// memberRoles is the audited state of an organization membership
+func memberRoles(m *model.OrganizationMember) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	return map[string]interface{}{"roles": m.Roles}
+}
+
+// groupMembers is the audited membership of a group
+func groupMembers(g *models.Group) map[string]interface{} {
+	return map[string]interface{}{"members": g.MemberIDs()}
+}
+
+// groupGrants is the audited grants of a group
+func groupGrants(g *model.Group) map[string]interface{} {
+	return map[string]interface{}{"roles": g.Roles, "permissions": g.Permissions}
+}
+
+// products forwards the products of the topic's events to the subscriber
+// until it goes away
+func (r *subscriptionResolver) products(ctx context.Context, topic string, id *string) (<-chan *model.Product, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	dbRecords, err := r.Services.ProductsService.Subscribe(ctx, cu, topic, id)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(chan *model.Product)
+	go func() {
+		defer close(results)
+		for dbRec := range dbRecords {
+			select {
+			case results <- transformations.DBProductToGQLProduct(dbRec):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return results, nil
+}
@@ -6,9 +6,16 @@ package gql
 import (
 	"context"
 
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/txbrown/gqlgen-api-starter/internal/audit"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/common"
 	"github.com/txbrown/gqlgen-api-starter/internal/gql/generated"
 	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/relay"
 	"github.com/txbrown/gqlgen-api-starter/internal/gql/transformations"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/pagination"
+	"github.com/txbrown/gqlgen-api-starter/internal/pubsub"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
 )
 
 func (r *mutationResolver) CreateProduct(ctx context.Context, input model.ProductInput) (*model.Product, error) {
@@ -19,17 +26,91 @@ func (r *mutationResolver) CreateProduct(ctx context.Context, input model.Produc
 		return nil, err
 	}
 
-	err = r.Services.ProductsService.Create(dbo)
+	err = r.Services.ProductsService.Create(cu, dbo)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return transformations.DBProductToGQLProduct(dbo), nil
+	product := transformations.DBProductToGQLProduct(dbo)
+	audit.SetTarget(ctx, consts.EntityNames.Products, product.ID)
+	audit.SetChange(ctx, nil, product)
+
+	return product, nil
+}
+
+func (r *mutationResolver) UpdateProduct(ctx context.Context, id string, input model.ProductInput) (*model.Product, error) {
+	cu := getCurrentUser(ctx)
+
+	id, err := relay.Key(id, relay.TypeProduct)
+	if err != nil {
+		return nil, err
+	}
+
+	audit.SetTarget(ctx, consts.EntityNames.Products, id)
+	before, err := r.Services.ProductsService.FindById(cu, id)
+	if err != nil {
+		return nil, err
+	}
+
+	dbo, err := r.Services.ProductsService.Update(cu, id, input)
+	if err != nil {
+		return nil, err
+	}
+
+	product := transformations.DBProductToGQLProduct(dbo)
+	audit.SetChange(ctx, transformations.DBProductToGQLProduct(before), product)
+
+	return product, nil
+}
+
+func (r *productResolver) ID(ctx context.Context, obj *model.Product) (string, error) {
+	return relay.ToGlobalID(relay.TypeProduct, obj.ID), nil
 }
 
-func (r *queryResolver) Products(ctx context.Context, id *string, filters []*model.QueryFilter, limit *int, offset *int, orderBy *string, sortDirection *string) ([]*model.Product, error) {
-	dbRecords, err := r.Services.ProductsService.Products(id, filters, limit, offset, orderBy, sortDirection)
+func (r *queryResolver) Node(ctx context.Context, id string) (model.Node, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	return r.node(cu, id)
+}
+
+func (r *queryResolver) Nodes(ctx context.Context, ids []string) ([]model.Node, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	// each id fails on its own, the others are still returned
+	results := make([]model.Node, len(ids))
+	for i, id := range ids {
+		node, err := r.node(cu, id)
+		if err != nil {
+			graphql.AddError(ctx, err)
+			continue
+		}
+		results[i] = node
+	}
+	return results, nil
+}
+
+func (r *queryResolver) Products(ctx context.Context, id *string, filters []*model.QueryFilter, where *model.FilterGroup, limit *int, offset *int, sort []*model.ProductSortInput, orderBy *string, sortDirection *string) ([]*model.Product, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	sorts, err := transformations.GQLProductSortToSorts(sort, orderBy, sortDirection)
+	if err != nil {
+		return nil, err
+	}
+	id, err = nodeKey(id, relay.TypeProduct)
+	if err != nil {
+		return nil, err
+	}
+	dbRecords, err := r.Services.ProductsService.Products(cu, id, filters, where, limit, offset, sorts)
 
 	if err != nil {
 		return nil, err
@@ -44,11 +125,93 @@ func (r *queryResolver) Products(ctx context.Context, id *string, filters []*mod
 	return results, nil
 }
 
+func (r *queryResolver) ProductList(ctx context.Context, id *string, filters []*model.QueryFilter, where *model.FilterGroup, limit *int, offset *int, sort []*model.ProductSortInput, orderBy *string, sortDirection *string) (*model.Products, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	sorts, err := transformations.GQLProductSortToSorts(sort, orderBy, sortDirection)
+	if err != nil {
+		return nil, err
+	}
+	id, err = nodeKey(id, relay.TypeProduct)
+	if err != nil {
+		return nil, err
+	}
+	dbRecords, more, err := r.Services.ProductsService.List(cu, id, filters, where, limit, offset, sorts)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &model.Products{HasNextPage: more, List: []*model.Product{}}
+	for _, dbRec := range dbRecords {
+		record.List = append(record.List, transformations.DBProductToGQLProduct(dbRec))
+	}
+	if isSelected(ctx, "count") {
+		count, err := r.Services.ProductsService.Count(cu, id, filters, where)
+		if err != nil {
+			return nil, err
+		}
+		total := int(count)
+		record.Count = &total
+	}
+
+	return record, nil
+}
+
+func (r *queryResolver) ProductsConnection(ctx context.Context, first *int, after *string, last *int, before *string, filters []*model.QueryFilter, where *model.FilterGroup, orderBy *string, sortDirection *string) (*model.ProductConnection, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	dbRecords, page, err := r.Services.ProductsService.Page(cu, filters, where, pagination.Args{
+		First: first, After: after, Last: last, Before: before,
+		OrderBy: valueOr(orderBy, "id"), Direction: valueOr(sortDirection, "ASC"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	connection := transformations.DBProductsToGQLProductConnection(dbRecords, page)
+	if isSelected(ctx, "totalCount") {
+		count, err := r.Services.ProductsService.Count(cu, nil, filters, where)
+		if err != nil {
+			return nil, err
+		}
+		total := int(count)
+		connection.TotalCount = &total
+	}
+
+	return connection, nil
+}
+
+func (r *subscriptionResolver) ProductCreated(ctx context.Context) (<-chan *model.Product, error) {
+	return r.products(ctx, pubsub.TopicProductCreated, nil)
+}
+
+func (r *subscriptionResolver) ProductUpdated(ctx context.Context, id *string) (<-chan *model.Product, error) {
+	id, err := nodeKey(id, relay.TypeProduct)
+	if err != nil {
+		return nil, err
+	}
+	return r.products(ctx, pubsub.TopicProductUpdated, id)
+}
+
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
+// Product returns generated.ProductResolver implementation.
+func (r *Resolver) Product() generated.ProductResolver { return &productResolver{r} }
+
 // Query returns generated.QueryResolver implementation.
 func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 
+// Subscription returns generated.SubscriptionResolver implementation.
+func (r *Resolver) Subscription() generated.SubscriptionResolver { return &subscriptionResolver{r} }
+
 type mutationResolver struct{ *Resolver }
+type productResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
@@ -0,0 +1,62 @@
+package transformations
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/gofrs/uuid"
+	gql "github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	dbm "github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+)
+
+// DBAuditEntryToGQLAuditEntry transforms [audit entry] db input to gql type
+func DBAuditEntryToGQLAuditEntry(i *dbm.AuditEntry) *gql.AuditEntry {
+	if i == nil {
+		return nil
+	}
+	o := &gql.AuditEntry{
+		ID:             strconv.FormatInt(i.ID, 10),
+		Event:          i.Event,
+		ActorID:        optionalID(i.ActorID),
+		ImpersonatorID: optionalID(i.ImpersonatorID),
+		OrganizationID: optionalID(i.OrganizationID),
+		Operation:      optionalString(i.Operation),
+		Variables:      decodeJSON(i.Variables),
+		TargetEntity:   optionalString(i.TargetEntity),
+		TargetID:       optionalString(i.TargetID),
+		Changes:        decodeJSON(i.Changes),
+		IP:             optionalString(i.IP),
+		Outcome:        gql.AuditOutcome(i.Outcome),
+		Error:          optionalString(i.Error),
+	}
+	if i.CreatedAt != nil {
+		o.CreatedAt = *i.CreatedAt
+	}
+	return o
+}
+
+func optionalID(id *uuid.UUID) *string {
+	if id == nil {
+		return nil
+	}
+	s := id.String()
+	return &s
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func decodeJSON(s *string) interface{} {
+	if s == nil {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(*s), &v); err != nil {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,20 @@
+package transformations
+
+import (
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	dbm "github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+)
+
+// DBFileToGQLFile transforms [file] db input to gql type
+func DBFileToGQLFile(i *dbm.File) *model.File {
+	return &model.File{
+		ID:          i.ID.String(),
+		OwnerUserID: i.OwnerID.String(),
+		StorageKey:  i.StorageKey,
+		Name:        i.Name,
+		ContentType: i.ContentType,
+		Size:        i.Size,
+		Checksum:    i.Checksum,
+		CreatedAt:   i.CreatedAt,
+	}
+}
@@ -0,0 +1,42 @@
+package transformations
+
+import (
+	gql "github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	dbm "github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+)
+
+// DBGroupToGQLGroup transforms [group] db input to gql type
+func DBGroupToGQLGroup(i *dbm.Group) *gql.Group {
+	if i == nil {
+		return nil
+	}
+	roles := []string{}
+	for _, r := range i.Roles {
+		roles = append(roles, r.Name)
+	}
+	permissions := []string{}
+	for _, p := range i.Permissions {
+		permissions = append(permissions, p.Tag)
+	}
+	members := []*gql.User{}
+	for j := range i.Members {
+		members = append(members, DBUserToGQLUser(&i.Members[j]))
+	}
+	o := &gql.Group{
+		ID:          i.ID.String(),
+		Name:        i.Name,
+		Roles:       roles,
+		Permissions: permissions,
+		Members:     members,
+		CreatedAt:   i.CreatedAt,
+		UpdatedAt:   i.UpdatedAt,
+	}
+	if i.Description != "" {
+		o.Description = &i.Description
+	}
+	if i.ParentID != nil {
+		parentID := i.ParentID.String()
+		o.ParentID = &parentID
+	}
+	return o
+}
@@ -0,0 +1,35 @@
+package transformations
+
+import (
+	gql "github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	dbm "github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+)
+
+// DBOrganizationToGQLOrganization transforms [organization] db input to gql type
+func DBOrganizationToGQLOrganization(i *dbm.Organization) *gql.Organization {
+	if i == nil {
+		return nil
+	}
+	return &gql.Organization{
+		ID:        i.ID.String(),
+		Name:      i.Name,
+		Slug:      i.Slug,
+		CreatedAt: i.CreatedAt,
+	}
+}
+
+// DBOrganizationMemberToGQLOrganizationMember transforms [organization member] db input to gql type
+func DBOrganizationMemberToGQLOrganizationMember(i *dbm.OrganizationMember) *gql.OrganizationMember {
+	if i == nil {
+		return nil
+	}
+	roles := []string{}
+	for _, r := range i.Roles {
+		roles = append(roles, r.Name)
+	}
+	return &gql.OrganizationMember{
+		Organization: DBOrganizationToGQLOrganization(&i.Organization),
+		User:         DBUserToGQLUser(&i.User),
+		Roles:        roles,
+	}
+}
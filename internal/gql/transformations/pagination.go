@@ -0,0 +1,35 @@
+package transformations
+
+import (
+	gql "github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	dbm "github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/pagination"
+)
+
+// PageToGQLPageInfo transforms [page] info to gql type
+func PageToGQLPageInfo(p *pagination.Page) *gql.PageInfo {
+	return &gql.PageInfo{
+		HasNextPage:     p.Info.HasNextPage,
+		HasPreviousPage: p.Info.HasPreviousPage,
+		StartCursor:     p.Info.StartCursor,
+		EndCursor:       p.Info.EndCursor,
+	}
+}
+
+// DBProductsToGQLProductConnection transforms a [products] page to gql type
+func DBProductsToGQLProductConnection(i []*dbm.Product, p *pagination.Page) *gql.ProductConnection {
+	edges := []*gql.ProductEdge{}
+	for j, dbRec := range i {
+		edges = append(edges, &gql.ProductEdge{Cursor: p.Cursors[j], Node: DBProductToGQLProduct(dbRec)})
+	}
+	return &gql.ProductConnection{Edges: edges, PageInfo: PageToGQLPageInfo(p)}
+}
+
+// DBUsersToGQLUserConnection transforms a [users] page to gql type
+func DBUsersToGQLUserConnection(i []*dbm.User, p *pagination.Page) *gql.UserConnection {
+	edges := []*gql.UserEdge{}
+	for j, dbRec := range i {
+		edges = append(edges, &gql.UserEdge{Cursor: p.Cursors[j], Node: DBUserToGQLUser(dbRec)})
+	}
+	return &gql.UserConnection{Edges: edges, PageInfo: PageToGQLPageInfo(p)}
+}
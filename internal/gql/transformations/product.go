@@ -8,8 +8,9 @@ import (
 
 func GQLProductInputToDBProduct(i *model.ProductInput, update bool, u *dbm.User) (*models.Product, error) {
 	o := &models.Product{
-		Name:  i.Name,
-		Price: i.Price,
+		Name:        i.Name,
+		Description: i.Description,
+		Price:       i.Price,
 	}
 
 	return o, nil
@@ -19,9 +20,10 @@ func GQLProductInputToDBProduct(i *model.ProductInput, update bool, u *dbm.User)
 func DBProductToGQLProduct(i *dbm.Product) *model.Product {
 
 	o := &model.Product{
-		ID:    i.ID.String(),
-		Name:  i.Name,
-		Price: i.Price,
+		ID:          i.ID.String(),
+		Name:        i.Name,
+		Description: i.Description,
+		Price:       i.Price,
 	}
 
 	return o
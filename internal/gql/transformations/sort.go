@@ -0,0 +1,53 @@
+package transformations
+
+import (
+	"fmt"
+
+	gql "github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/query"
+)
+
+// GQLProductSortToSorts transforms the [products] ordering arguments, the
+// deprecated orderBy and sortDirection can't be combined with sort
+func GQLProductSortToSorts(sort []*gql.ProductSortInput, orderBy *string, sortDirection *string) ([]query.Sort, error) {
+	if len(sort) == 0 {
+		return query.LegacySort(orderBy, sortDirection)
+	}
+	if orderBy != nil || sortDirection != nil {
+		return nil, errLegacySort
+	}
+	sorts := []query.Sort{}
+	for _, s := range sort {
+		sorts = append(sorts, gqlSort(s.Field.String(), s.Direction, s.Nulls))
+	}
+	return sorts, nil
+}
+
+// GQLUserSortToSorts transforms the [users] ordering arguments, the
+// deprecated orderBy and sortDirection can't be combined with sort
+func GQLUserSortToSorts(sort []*gql.UserSortInput, orderBy *string, sortDirection *string) ([]query.Sort, error) {
+	if len(sort) == 0 {
+		return query.LegacySort(orderBy, sortDirection)
+	}
+	if orderBy != nil || sortDirection != nil {
+		return nil, errLegacySort
+	}
+	sorts := []query.Sort{}
+	for _, s := range sort {
+		sorts = append(sorts, gqlSort(s.Field.String(), s.Direction, s.Nulls))
+	}
+	return sorts, nil
+}
+
+var errLegacySort = fmt.Errorf("%w: sort can't be combined with orderBy or sortDirection", query.ErrInvalidSort)
+
+func gqlSort(field string, direction *gql.SortDirection, nulls *gql.SortNulls) query.Sort {
+	s := query.Sort{Field: field}
+	if direction != nil {
+		s.Desc = *direction == gql.SortDirectionDesc
+	}
+	if nulls != nil {
+		s.Nulls = nulls.String()
+	}
+	return s
+}
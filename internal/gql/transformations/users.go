@@ -16,21 +16,20 @@ func DBUserToGQLUser(i *dbm.User) *gql.User {
 	if i == nil {
 		return nil
 	}
-	profiles := []*gql.UserProfile{}
-	for _, p := range i.UserProfiles {
-		profiles = append(profiles, DBUserProfileToGQLUserProfile(&p))
-	}
 	return &gql.User{
 		AvatarURL:   i.AvatarURL,
 		ID:          i.ID.String(),
-		Email:       i.Email,
+		CreatedByID: optionalID(i.CreatedByID),
+		UpdatedByID: optionalID(i.UpdatedByID),
+		Email:       &i.Email,
 		Name:        i.Name,
 		FirstName:   i.FirstName,
 		LastName:    i.LastName,
 		NickName:    i.NickName,
 		Description: i.Description,
 		Location:    i.Location,
-		Profiles:    profiles,
+		Roles:       i.RoleNames(),
+		Permissions: i.PermissionTags(),
 		CreatedAt:   i.CreatedAt,
 		UpdatedAt:   i.UpdatedAt,
 	}
@@ -44,6 +43,7 @@ func DBUserProfileToGQLUserProfile(i *dbm.UserProfile) *gql.UserProfile {
 	return &gql.UserProfile{
 		AvatarURL:      &i.AvatarURL,
 		ID:             i.ID,
+		UserID:         i.UserID.String(),
 		ExternalUserID: &i.ExternalUserID,
 		Email:          i.Email,
 		Name:           &i.Name,
@@ -54,8 +54,8 @@ func DBUserProfileToGQLUserProfile(i *dbm.UserProfile) *gql.UserProfile {
 		Location:       &i.Location,
 		CreatedAt:      *i.CreatedAt,
 		UpdatedAt:      i.UpdatedAt,
-		CreatedBy:      DBUserToGQLUser(i.CreatedBy),
-		UpdatedBy:      DBUserToGQLUser(i.UpdatedBy),
+		CreatedByID:    optionalID(i.CreatedByID),
+		UpdatedByID:    optionalID(i.UpdatedByID),
 	}
 }
 
@@ -82,9 +82,9 @@ func GQLInputUserToDBUser(i *gql.UserInput, update bool, u *dbm.User, ids ...str
 		o.Password = *i.Password
 	}
 	if !update {
-		o.CreatedBy = u
+		o.CreatedByID = userID(u)
 	}
-	o.UpdatedBy = u
+	o.UpdatedByID = userID(u)
 	if len(ids) > 0 {
 		updID, err := uuid.FromString(ids[0])
 		if err != nil {
@@ -115,9 +115,9 @@ func GQLInputUserToDBUserProfile(i *gql.UserInput, update bool, u *dbm.User, ids
 		o.LastName = *i.LastName
 	}
 	if !update {
-		o.CreatedBy = u
+		o.CreatedByID = userID(u)
 	}
-	o.UpdatedBy = u
+	o.UpdatedByID = userID(u)
 
 	return o, err
 }
@@ -131,10 +131,10 @@ func DBUserToDBUserProfile(i *dbm.User, update bool, u *dbm.User, ids ...string)
 	}
 
 	if !update {
-		o.CreatedBy = u
+		o.CreatedByID = userID(u)
 	}
 
-	o.UpdatedBy = u
+	o.UpdatedByID = userID(u)
 
 	return o, err
 }
@@ -235,3 +235,11 @@ func AppleUserInputToDBUserProfile(i *model.BasicUserInput, update bool, ids ...
 	}
 	return o, err
 }
+
+// userID is the id recorded as creator or updater, nil when nobody is acting
+func userID(u *dbm.User) *uuid.UUID {
+	if u == nil {
+		return nil
+	}
+	return &u.ID
+}
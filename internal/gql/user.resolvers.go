@@ -5,11 +5,18 @@ package gql
 
 import (
 	"context"
-	"fmt"
+	"strconv"
 
+	"github.com/txbrown/gqlgen-api-starter/internal/audit"
 	"github.com/txbrown/gqlgen-api-starter/internal/gql/common"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/generated"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/loaders"
 	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/relay"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/transformations"
 	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/pagination"
+	"github.com/txbrown/gqlgen-api-starter/internal/pubsub"
 	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
 )
 
@@ -18,11 +25,14 @@ func (r *mutationResolver) CreateUser(ctx context.Context, input model.UserInput
 	if cu == nil {
 		return nil, common.GqlUnauthorizedError(ctx)
 	}
-	if ok, err := cu.HasPermission(consts.Permissions.Create, consts.EntityNames.Users); !ok || err != nil {
+
+	user, err := r.Services.UsersService.CreateUpdate(input, false, cu)
+	if err != nil {
 		return nil, logger.Errorfn(consts.EntityNames.Users, err)
 	}
-
-	return r.Services.UsersService.CreateUpdate(input, false, cu)
+	audit.SetTarget(ctx, consts.EntityNames.Users, user.ID)
+	audit.SetChange(ctx, nil, user)
+	return user, nil
 }
 
 func (r *mutationResolver) UpdateUser(ctx context.Context, id string, input model.UserInput) (*model.User, error) {
@@ -30,40 +40,227 @@ func (r *mutationResolver) UpdateUser(ctx context.Context, id string, input mode
 	if cu == nil {
 		return nil, common.GqlUnauthorizedError(ctx)
 	}
-	if ok, err := cu.HasPermission(consts.Permissions.Create, consts.EntityNames.Users); !ok || err != nil {
+
+	id, err := relay.Key(id, relay.TypeUser)
+	if err != nil {
+		return nil, err
+	}
+	audit.SetTarget(ctx, consts.EntityNames.Users, id)
+	before, err := r.Services.UsersService.FindById(cu, id)
+	if err != nil {
 		return nil, logger.Errorfn(consts.EntityNames.Users, err)
 	}
 
-	return r.Services.UsersService.CreateUpdate(input, false, cu)
+	user, err := r.Services.UsersService.CreateUpdate(input, true, cu, id)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Users, err)
+	}
+	audit.SetChange(ctx, transformations.DBUserToGQLUser(before), user)
+	return user, nil
 }
 
-func (r *mutationResolver) UpdateUserProfile(ctx context.Context, input model.UserInput) (*model.User, error) {
-	panic(fmt.Errorf("not implemented"))
+func (r *mutationResolver) UpdateUserProfile(ctx context.Context, id *string, input model.UserProfileInput) (*model.User, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	id, err := nodeKey(id, relay.TypeUser)
+	if err != nil {
+		return nil, err
+	}
+	userID := cu.ID.String()
+	before := transformations.DBUserToGQLUser(cu)
+	if id != nil && *id != userID {
+		userID = *id
+		dbo, err := r.Services.UsersService.FindById(cu, userID)
+		if err != nil {
+			return nil, logger.Errorfn(consts.EntityNames.Users, err)
+		}
+		before = transformations.DBUserToGQLUser(dbo)
+	}
+	audit.SetTarget(ctx, consts.EntityNames.Users, userID)
+
+	user, err := r.Services.UsersService.UpdateProfile(cu, userID, input)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Users, err)
+	}
+	audit.SetChange(ctx, before, user)
+	return user, nil
 }
 
-func (r *mutationResolver) DeleteUser(ctx context.Context, id string) (bool, error) {
-	panic(fmt.Errorf("not implemented"))
+func (r *mutationResolver) DeleteUser(ctx context.Context, id *string) (bool, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return false, common.GqlUnauthorizedError(ctx)
+	}
+
+	id, err := nodeKey(id, relay.TypeUser)
+	if err != nil {
+		return false, err
+	}
+	userID := cu.ID.String()
+	if id != nil {
+		userID = *id
+	}
+	audit.SetTarget(ctx, consts.EntityNames.Users, userID)
+
+	deleted, err := r.Services.UsersService.Delete(cu, userID)
+	if err != nil {
+		return false, logger.Errorfn(consts.EntityNames.Users, err)
+	}
+	return deleted, nil
 }
 
 func (r *mutationResolver) SignInWithApple(ctx context.Context, input model.SignInWithAppleInput) (*model.SignInResponse, error) {
-	panic(fmt.Errorf("not implemented"))
+	return nil, common.GqlNotImplementedError(ctx)
 }
 
 func (r *mutationResolver) CreateUserAccount(ctx context.Context, input model.CreateUserAccountInput) (*model.User, error) {
-	panic(fmt.Errorf("not implemented"))
+	return nil, common.GqlNotImplementedError(ctx)
 }
 
 func (r *mutationResolver) SignIn(ctx context.Context, input model.SignInInput) (*model.SignInResponse, error) {
-	panic(fmt.Errorf("not implemented"))
+	return nil, common.GqlNotImplementedError(ctx)
+}
+
+func (r *queryResolver) Me(ctx context.Context) (*model.User, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+	return transformations.DBUserToGQLUser(cu), nil
+}
+
+func (r *queryResolver) Users(ctx context.Context, id *string, filters []*model.QueryFilter, where *model.FilterGroup, limit *int, offset *int, sort []*model.UserSortInput, orderBy *string, sortDirection *string) (*model.Users, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	sorts, err := transformations.GQLUserSortToSorts(sort, orderBy, sortDirection)
+	if err != nil {
+		return nil, err
+	}
+	id, err = nodeKey(id, relay.TypeUser)
+	if err != nil {
+		return nil, err
+	}
+	users, err := r.Services.UsersService.List(cu, id, filters, where, limit, offset, sorts)
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.Users, err)
+	}
+	if isSelected(ctx, "count") {
+		count, err := r.Services.UsersService.Count(cu, id, filters, where)
+		if err != nil {
+			return nil, logger.Errorfn(consts.EntityNames.Users, err)
+		}
+		total := int(count)
+		users.Count = &total
+	}
+	return users, nil
 }
 
-func (r *queryResolver) Users(ctx context.Context, id *string, filters []*model.QueryFilter, limit *int, offset *int, orderBy *string, sortDirection *string) (*model.Users, error) {
+func (r *queryResolver) UsersConnection(ctx context.Context, first *int, after *string, last *int, before *string, filters []*model.QueryFilter, where *model.FilterGroup, orderBy *string, sortDirection *string) (*model.UserConnection, error) {
 	cu := getCurrentUser(ctx)
 	if cu == nil {
 		return nil, common.GqlUnauthorizedError(ctx)
 	}
-	if ok, err := cu.HasPermission(consts.Permissions.List, consts.EntityNames.Users); !ok || err != nil {
+
+	dbRecords, page, err := r.Services.UsersService.Page(cu, filters, where, pagination.Args{
+		First: first, After: after, Last: last, Before: before,
+		OrderBy: valueOr(orderBy, "id"), Direction: valueOr(sortDirection, "ASC"),
+	})
+	if err != nil {
 		return nil, logger.Errorfn(consts.EntityNames.Users, err)
 	}
-	return r.Services.UsersService.List(id, filters, limit, offset, orderBy, sortDirection)
+
+	connection := transformations.DBUsersToGQLUserConnection(dbRecords, page)
+	if isSelected(ctx, "totalCount") {
+		count, err := r.Services.UsersService.Count(cu, nil, filters, where)
+		if err != nil {
+			return nil, logger.Errorfn(consts.EntityNames.Users, err)
+		}
+		total := int(count)
+		connection.TotalCount = &total
+	}
+
+	return connection, nil
+}
+
+func (r *subscriptionResolver) UserUpdated(ctx context.Context, id *string) (<-chan *model.User, error) {
+	cu := getCurrentUser(ctx)
+	if cu == nil {
+		return nil, common.GqlUnauthorizedError(ctx)
+	}
+
+	id, err := nodeKey(id, relay.TypeUser)
+	if err != nil {
+		return nil, err
+	}
+	dbRecords, err := r.Services.UsersService.Subscribe(ctx, cu, pubsub.TopicUserUpdated, id)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(chan *model.User)
+	go func() {
+		defer close(results)
+		for dbRec := range dbRecords {
+			// the relations of each event are loaded fresh
+			loaders.FromContext(ctx).Clear()
+			select {
+			case results <- transformations.DBUserToGQLUser(dbRec):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return results, nil
+}
+
+func (r *userResolver) ID(ctx context.Context, obj *model.User) (string, error) {
+	return relay.ToGlobalID(relay.TypeUser, obj.ID), nil
+}
+
+func (r *userResolver) Profiles(ctx context.Context, obj *model.User, limit *int, offset *int) ([]*model.UserProfile, error) {
+	dbRecords, err := loaders.FromContext(ctx).Profiles(obj.ID, intOr(limit, 10), intOr(offset, 0))
+	if err != nil {
+		return nil, logger.Errorfn(consts.EntityNames.UserProfiles, err)
+	}
+	profiles := []*model.UserProfile{}
+	for _, dbo := range dbRecords {
+		profiles = append(profiles, transformations.DBUserProfileToGQLUserProfile(dbo))
+	}
+	return profiles, nil
+}
+
+func (r *userResolver) CreatedBy(ctx context.Context, obj *model.User) (*model.User, error) {
+	return loadUser(ctx, obj.CreatedByID)
+}
+
+func (r *userResolver) UpdatedBy(ctx context.Context, obj *model.User) (*model.User, error) {
+	return loadUser(ctx, obj.UpdatedByID)
 }
+
+func (r *userProfileResolver) ID(ctx context.Context, obj *model.UserProfile) (string, error) {
+	return relay.ToGlobalID(relay.TypeUserProfile, strconv.Itoa(obj.ID)), nil
+}
+
+func (r *userProfileResolver) CreatedBy(ctx context.Context, obj *model.UserProfile) (*model.User, error) {
+	return loadUser(ctx, obj.CreatedByID)
+}
+
+func (r *userProfileResolver) UpdatedBy(ctx context.Context, obj *model.UserProfile) (*model.User, error) {
+	return loadUser(ctx, obj.UpdatedByID)
+}
+
+// User returns generated.UserResolver implementation.
+func (r *Resolver) User() generated.UserResolver { return &userResolver{r} }
+
+// UserProfile returns generated.UserProfileResolver implementation.
+func (r *Resolver) UserProfile() generated.UserProfileResolver { return &userProfileResolver{r} }
+
+type userResolver struct{ *Resolver }
+type userProfileResolver struct{ *Resolver }
@@ -8,6 +8,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/markbates/goth/gothic"
+	"github.com/txbrown/gqlgen-api-starter/internal/audit"
 	"github.com/txbrown/gqlgen-api-starter/internal/logger"
 	"github.com/txbrown/gqlgen-api-starter/internal/services"
 	"github.com/txbrown/gqlgen-api-starter/pkg/auth"
@@ -30,12 +31,15 @@ func Begin() gin.HandlerFunc {
 }
 
 // Callback callback to complete auth provider flow
-func Callback(cfg *utils.ServerConfig, usersService services.UsersService) gin.HandlerFunc {
+func Callback(cfg *utils.ServerConfig, usersService services.UsersService, r audit.Recorder) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// You have to add value context with provider name to get provider name in GetProviderName method
 		c.Request = addProviderToContext(c, c.Param(string(utils.ProjectContextKeys.ProviderCtxKey)))
+		e := newAuthEntry(c, cfg, audit.EventLogin)
 		user, err := gothic.CompleteUserAuth(c.Writer, c.Request)
 		if err != nil {
+			audit.Fail(e, err)
+			audit.Log(r, e)
 			c.AbortWithError(http.StatusInternalServerError, err)
 			return
 		}
@@ -44,9 +48,14 @@ func Callback(cfg *utils.ServerConfig, usersService services.UsersService) gin.H
 		if err != nil {
 			if u, err = usersService.UpsertUserProfile(&user); err != nil {
 				logger.Errorf("[Auth.CallBack.UserLoggedIn.UpsertUserProfile.Error]: %v", err)
+				audit.Fail(e, err)
+				audit.Log(r, e)
 				c.AbortWithError(http.StatusInternalServerError, err)
+				return
 			}
 		}
+		e.ActorID = &u.ID
+		audit.Log(r, e)
 		// logger.Debug("[Auth.CallBack.UserLoggedIn.USER]: ", u)
 		logger.Debug("[Auth.CallBack.UserLoggedIn]: ", u.ID)
 		jwtToken := jwt.NewWithClaims(jwt.GetSigningMethod(cfg.JWT.Algorithm), auth.Claims{
@@ -79,10 +88,12 @@ func Callback(cfg *utils.ServerConfig, usersService services.UsersService) gin.H
 }
 
 // Logout logs out of the auth provider
-func Logout() gin.HandlerFunc {
+func Logout(cfg *utils.ServerConfig, r audit.Recorder) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Request = addProviderToContext(c, c.Param("provider"))
-		gothic.Logout(c.Writer, c.Request)
+		e := newAuthEntry(c, cfg, audit.EventLogout)
+		audit.Fail(e, gothic.Logout(c.Writer, c.Request))
+		audit.Log(r, e)
 		c.Writer.Header().Set("Location", "/")
 		c.Writer.WriteHeader(http.StatusTemporaryRedirect)
 	}
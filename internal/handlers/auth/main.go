@@ -5,6 +5,8 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/txbrown/gqlgen-api-starter/internal/audit"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
 	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
 )
 
@@ -12,3 +14,12 @@ func addProviderToContext(c *gin.Context, value interface{}) *http.Request {
 	return c.Request.WithContext(context.WithValue(c.Request.Context(),
 		string(utils.ProjectContextKeys.ProviderCtxKey), value))
 }
+
+// newAuthEntry returns the audit entry of an auth event, the operation is the
+// auth provider
+func newAuthEntry(c *gin.Context, cfg *utils.ServerConfig, event string) *models.AuditEntry {
+	e := audit.NewEntry(c.Request.Context(), event)
+	e.Operation = c.Param("provider")
+	e.IP = utils.ClientIP(c.Request, cfg.TrustedProxies)
+	return e
+}
@@ -1,13 +1,18 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/txbrown/gqlgen-api-starter/internal/audit"
 	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
 	"github.com/txbrown/gqlgen-api-starter/internal/services"
 	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
 
 	"github.com/gin-gonic/gin"
 )
@@ -29,63 +34,174 @@ func getTokenFromAuthorizationHeader(headers http.Header) string {
 	return token
 }
 
+// activateOrganization sets the organization the user acts in, the id comes
+// from the token's org claim or, for API keys, the organization header. The
+// grants of the user's groups in it are added to the user's own
+func activateOrganization(os services.OrganizationsService, gs services.GroupsService, user *models.User, organizationID string) error {
+	if user == nil {
+		return nil
+	}
+	if organizationID != "" {
+		if err := os.Activate(user, organizationID); err != nil {
+			return err
+		}
+	}
+	return gs.ApplyGrants(user)
+}
+
+// impersonate returns the user a platform admin acts on behalf of, acting in
+// the same organization as the admin asked for
+func impersonate(c *gin.Context, us services.UsersService, os services.OrganizationsService, gs services.GroupsService, r audit.Recorder, admin *models.User, organizationID string) (*models.User, error) {
+	id := c.GetHeader(ImpersonateHeader)
+	e := audit.NewEntry(c.Request.Context(), audit.EventImpersonate)
+	e.ImpersonatorID = &admin.ID
+	e.TargetEntity = consts.EntityNames.Users
+	e.TargetID = id
+
+	user, err := func() (*models.User, error) {
+		if !admin.IsPlatformAdmin() {
+			return nil, ErrForbidden
+		}
+		user, err := us.FindById(admin, id)
+		if err != nil {
+			return nil, err
+		}
+		return user, activateOrganization(os, gs, user, organizationID)
+	}()
+	if user != nil {
+		e.ActorID = &user.ID
+		e.OrganizationID = user.ActiveOrganizationID
+	}
+	audit.Fail(e, err)
+	audit.Log(r, e)
+	return user, err
+}
+
+// setUser puts the authenticated user in the request's context, or the user
+// it impersonates
+func setUser(c *gin.Context, us services.UsersService, os services.OrganizationsService, gs services.GroupsService, r audit.Recorder, user *models.User, organizationID string) error {
+	if c.GetHeader(ImpersonateHeader) != "" {
+		target, err := impersonate(c, us, os, gs, r, user, organizationID)
+		if err != nil {
+			return err
+		}
+		c.Request = addToContext(c, utils.ProjectContextKeys.ImpersonatorCtxKey, user)
+		user = target
+	}
+	c.Request = addToContext(c, utils.ProjectContextKeys.UserCtxKey, user)
+	return nil
+}
+
 // Middleware wraps the request with auth middleware
-func Middleware(path string, cfg *utils.ServerConfig, us services.UsersService) gin.HandlerFunc {
+func Middleware(path string, cfg *utils.ServerConfig, us services.UsersService, os services.OrganizationsService, gs services.GroupsService, r audit.Recorder) gin.HandlerFunc {
 	logger.Info("[Auth.Middleware] Applied to path: ", path)
 	return gin.HandlerFunc(func(c *gin.Context) {
-		if a, err := ParseAPIKey(c, cfg); err == nil {
-			user, err := us.FindUserByAPIKey(a)
-			if err != nil {
-				logger.Info("fails here 1")
-				logger.Info(err)
-				authError(c, ErrForbidden)
-			}
-			c.Request = addToContext(c, utils.ProjectContextKeys.UserCtxKey, user)
-			if user != nil {
-				logger.Info("User: ", user.ID)
-			}
-			c.Next()
-		} else {
-			if err != ErrEmptyAPIKeyHeader {
-				authError(c, err)
-			} else if token := getTokenFromAuthorizationHeader(c.Request.Header); token != "" {
-				t, err := ParseToken(c, cfg)
-				if err != nil {
-					authError(c, err)
-				} else {
-					// goth.ContextForClient(c.)
-					if claims, ok := t.Claims.(jwt.MapClaims); ok {
-						if claims["exp"] != nil {
-							issuer := claims["iss"].(string)
-							userid := claims["jti"].(string)
-							email := claims["email"].(string)
-							if claims["aud"] != nil {
-								audiences := claims["aud"].(interface{})
-								logger.Warnf("\n\naudiences: %s\n\n", audiences)
-							}
-							if claims["alg"] != nil {
-								algo := claims["alg"].(string)
-								logger.Warnf("\n\nalgo: %s\n\n", algo)
-							}
-							// TODO: Verify token with each provider's JWKs
-							if user, err := us.FindUserByJWT(email, issuer, userid); err != nil {
-								logger.Info("fails here 2")
-								logger.Info(err)
-								authError(c, ErrForbidden)
-							} else {
-								c.Request = addToContext(c, utils.ProjectContextKeys.UserCtxKey, user)
-								c.Next()
-							}
-						} else {
-							authError(c, ErrMissingExpField)
-						}
-					} else {
-						authError(c, err)
-					}
-				}
-			} else {
-				c.Next()
-			}
+		c.Request = addToContext(c, utils.ProjectContextKeys.ClientIPCtxKey, utils.ClientIP(c.Request, cfg.TrustedProxies))
+		if err := authenticate(c, cfg, us, os, gs, r); err != nil {
+			authError(c, err)
+			return
 		}
+		c.Next()
 	})
 }
+
+// authenticate puts the user of the request's API key or token in its
+// context, requests with neither stay anonymous
+func authenticate(c *gin.Context, cfg *utils.ServerConfig, us services.UsersService, os services.OrganizationsService, gs services.GroupsService, r audit.Recorder) error {
+	a, err := ParseAPIKey(c, cfg)
+	if err == nil {
+		e := audit.NewEntry(c.Request.Context(), audit.EventAPIKey)
+		key, err := us.FindAPIKey(a)
+		if err != nil || key.ID == 0 {
+			logger.Info("fails here 1")
+			logger.Info(err)
+			audit.Fail(e, ErrForbidden)
+			audit.Log(r, e)
+			return ErrForbidden
+		}
+		user := &key.User
+		err = activateOrganization(os, gs, user, c.GetHeader(OrganizationHeader))
+		e.ActorID = &user.ID
+		e.OrganizationID = user.ActiveOrganizationID
+		audit.Fail(e, err)
+		audit.Log(r, e)
+		if err != nil {
+			return err
+		}
+		if err := setUser(c, us, os, gs, r, user, c.GetHeader(OrganizationHeader)); err != nil {
+			return ErrForbidden
+		}
+		c.Request = addToContext(c, utils.ProjectContextKeys.APIKeyCtxKey, key)
+		logger.Info("User: ", user.ID)
+		return nil
+	}
+	if err != ErrEmptyAPIKeyHeader {
+		return err
+	}
+	if getTokenFromAuthorizationHeader(c.Request.Header) == "" {
+		return nil
+	}
+
+	t, err := ParseToken(c, cfg)
+	if err != nil {
+		return err
+	}
+	// goth.ContextForClient(c.)
+	claims, ok := t.Claims.(jwt.MapClaims)
+	if !ok {
+		return ErrNoClaims
+	}
+	if claims["exp"] == nil {
+		return ErrMissingExpField
+	}
+	issuer := claims["iss"].(string)
+	userid := claims["jti"].(string)
+	email := claims["email"].(string)
+	if claims["aud"] != nil {
+		audiences := claims["aud"].(interface{})
+		logger.Warnf("\n\naudiences: %s\n\n", audiences)
+	}
+	if claims["alg"] != nil {
+		algo := claims["alg"].(string)
+		logger.Warnf("\n\nalgo: %s\n\n", algo)
+	}
+	// TODO: Verify token with each provider's JWKs
+	user, err := us.FindUserByJWT(email, issuer, userid)
+	if err != nil {
+		logger.Info("fails here 2")
+		logger.Info(err)
+		return ErrForbidden
+	}
+	org, _ := claims["org"].(string)
+	if activateOrganization(os, gs, user, org) != nil {
+		return ErrForbidden
+	}
+	if err := setUser(c, us, os, gs, r, user, org); err != nil {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// WebsocketInit authenticates websocket connections from their connection_init
+// payload, which carries the headers browsers can't set on websockets, e.g.
+// {"Authorization": "Bearer <token>", "x-organization-id": "<id>"}
+func WebsocketInit(cfg *utils.ServerConfig, us services.UsersService, os services.OrganizationsService, gs services.GroupsService, r audit.Recorder) transport.WebsocketInitFunc {
+	return func(ctx context.Context, payload transport.InitPayload) (context.Context, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+		if err != nil {
+			return ctx, err
+		}
+		for _, h := range []string{"Authorization", APIKeyHeader, OrganizationHeader, ImpersonateHeader} {
+			if v := payload.GetString(h); v != "" {
+				req.Header.Set(h, v)
+			} else if v := payload.GetString(strings.ToLower(h)); v != "" {
+				req.Header.Set(h, v)
+			}
+		}
+		c := &gin.Context{Request: req}
+		if err := authenticate(c, cfg, us, os, gs, r); err != nil {
+			return ctx, err
+		}
+		return c.Request.Context(), nil
+	}
+}
@@ -16,9 +16,20 @@ var (
 	// APIKeyHeader The API key header name
 	APIKeyHeader = utils.MustGet("AUTH_API_KEY_HEADER")
 
+	// OrganizationHeader selects the active organization of API key requests,
+	// tokens carry it in their org claim
+	OrganizationHeader = "x-organization-id"
+
+	// ImpersonateHeader lets platform admins act on behalf of the user with
+	// the given id, the admin is recorded as the impersonator in the audit log
+	ImpersonateHeader = "x-impersonate-user"
+
 	// TokenHeadName is a string in the header. Default value is "Bearer"
 	TokenHeadName = "Bearer"
 
+	// TokenCookie is the cookie of the session token set on sign in
+	TokenCookie = "jwt"
+
 	// APIKeyLookup is a string in the form of "<source>:<name>" that is used
 	// to extract token from the request.
 	// Optional. Default value "header:Authorization".
@@ -35,7 +46,7 @@ var (
 	// - "header:<name>"
 	// - "query:<name>"
 	// - "cookie:<name>"
-	TokenLookup = "query:token,cookie:jwt,header:Authorization"
+	TokenLookup = "query:token,cookie:" + TokenCookie + ",header:Authorization"
 
 	// ErrNoClaims when HTTP status 403 is given
 	ErrNoClaims = errors.New("invalid token")
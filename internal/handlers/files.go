@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/txbrown/gqlgen-api-starter/internal/services"
+)
+
+// Download serves the files of the local storage from their signed URLs
+func Download(fs services.FilesService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimPrefix(c.Param("key"), "/")
+		name := c.Query("name")
+		f, err := fs.Open(key, name, c.Query("expires"), c.Query("signature"))
+		if os.IsNotExist(err) {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+		c.Header("X-Content-Type-Options", "nosniff")
+		http.ServeContent(c.Writer, c.Request, name, info.ModTime(), f)
+	}
+}
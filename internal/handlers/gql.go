@@ -1,34 +1,112 @@
 package handlers
 
 import (
-	"github.com/99designs/gqlgen/handler"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/gin-gonic/gin"
+	"github.com/txbrown/gqlgen-api-starter/internal/audit"
 	"github.com/txbrown/gqlgen-api-starter/internal/gql"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/common"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/console"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/constraints"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/directives"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/extensions"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/federation"
 	"github.com/txbrown/gqlgen-api-starter/internal/gql/generated"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/graphqlws"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/limits"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/loaders"
+	"github.com/txbrown/gqlgen-api-starter/internal/handlers/auth/middleware"
 
 	"github.com/txbrown/gqlgen-api-starter/internal/services"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
 )
 
 // GraphqlHandler defines the GQLGen GraphQL server handler
-func GraphqlHandler(services *services.Services) gin.HandlerFunc {
+func GraphqlHandler(cfg *utils.ServerConfig, services *services.Services) (gin.HandlerFunc, error) {
 	// NewExecutableSchema and Config are in the generated.go file
 	c := generated.Config{
 		Resolvers: &gql.Resolver{
+			Config:   cfg,
 			Services: services,
 		},
+		Directives: directives.Root(services.Policy),
 	}
 
-	h := handler.GraphQL(generated.NewExecutableSchema(c))
+	h := handler.New(limits.WithCosts(generated.NewExecutableSchema(c)))
+	h.SetErrorPresenter(common.ErrorPresenter(cfg))
+	h.SetRecoverFunc(common.Recover)
+	h.AddTransport(transport.Options{})
+	for _, name := range cfg.GraphQL.Transports {
+		ts, err := transports(name, cfg, services)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range ts {
+			h.AddTransport(t)
+		}
+	}
+	h.SetQueryCache(lru.New(cfg.GraphQL.QueryCacheSize))
+	h.Use(console.Introspection{Access: console.Access{
+		Enabled: cfg.GraphQL.IntrospectionEnabled,
+		Roles:   cfg.GraphQL.IntrospectionRoles,
+	}})
+	h.Use(&constraints.Extension{})
+	h.Use(federation.Subgraph{Enabled: cfg.GraphQL.FederationEnabled})
+	exts, err := extensions.Build(cfg.GraphQL.Extensions, cfg, services)
+	if err != nil {
+		return nil, err
+	}
+	for _, ext := range exts {
+		h.Use(ext)
+	}
+	h.AroundOperations(loaders.Middleware(services.UsersService, services.ProductsService))
+	h.AroundFields(audit.FieldMiddleware(services.AuditService))
 
 	return func(c *gin.Context) {
-		h.ServeHTTP(c.Writer, c.Request)
+		ctx := context.WithValue(c.Request.Context(), utils.ProjectContextKeys.ResponseHeaderCtxKey, c.Writer.Header())
+		h.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+	}, nil
+}
+
+// transports returns the transports of the name, GET only serves queries,
+// mutations are refused on it
+func transports(name string, cfg *utils.ServerConfig, services *services.Services) ([]graphql.Transport, error) {
+	switch name {
+	case "post":
+		return []graphql.Transport{transport.POST{}}, nil
+	case "get":
+		return []graphql.Transport{transport.GET{}}, nil
+	case "multipart":
+		// Room for the operations and map parts next to the largest file
+		return []graphql.Transport{transport.MultipartForm{MaxUploadSize: cfg.Storage.MaxUploadSize + 1<<20}}, nil
+	case "websocket":
+		// graphql-transport-ws first, the legacy graphql-ws transport takes
+		// every upgrade request
+		wsInit := middleware.WebsocketInit(cfg, services.UsersService, services.OrganizationsService, services.GroupsService, services.AuditService)
+		return []graphql.Transport{
+			graphqlws.Transport{InitFunc: wsInit, KeepAlivePingInterval: 10 * time.Second},
+			transport.Websocket{InitFunc: wsInit, KeepAlivePingInterval: 10 * time.Second},
+		}, nil
 	}
+	return nil, fmt.Errorf("[GraphqlHandler] unknown transport: %s", name)
 }
 
-// PlaygroundHandler defines a handler to expose the Playground
-func PlaygroundHandler(path string) gin.HandlerFunc {
-	h := handler.Playground("Go GraphQL Server", path)
+// PlaygroundHandler defines a handler to expose the configured IDE, it calls
+// the endpoint with the cookie of the user's session
+func PlaygroundHandler(cfg *utils.ServerConfig, path string) (gin.HandlerFunc, error) {
+	access := console.Access{Enabled: cfg.GraphQL.IsPlaygroundEnabled, Roles: cfg.GraphQL.PlaygroundRoles}
+	h, err := console.IDE(cfg.GraphQL.IDE, "Go GraphQL Server", path, access)
+	if err != nil {
+		return nil, err
+	}
 	return func(c *gin.Context) {
 		h.ServeHTTP(c.Writer, c.Request)
-	}
+	}, nil
 }
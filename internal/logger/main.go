@@ -61,7 +61,7 @@ func Debugf(format string, args ...interface{}) {
 
 // Errorfn Log errors of a [fn] with format
 func Errorfn(fn string, err error) error {
-	outerr := fmt.Errorf("[%s]: %v", fn, err)
+	outerr := fmt.Errorf("[%s]: %w", fn, err)
 	logger.Errorln(outerr)
 	return outerr
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	log "github.com/txbrown/gqlgen-api-starter/internal/logger"
 	"github.com/txbrown/gqlgen-api-starter/internal/orm/migration"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/tenancy"
 	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
 
 	"gorm.io/driver/postgres"
@@ -48,9 +49,14 @@ func NewDB(cfg *utils.ServerConfig) (*gorm.DB, error) {
 		log.Panic("[ORM] err: ", err)
 	}
 
+	// Scope the tenant owned tables to the organization of the statement
+	if err := tenancy.RegisterCallbacks(db); err != nil {
+		log.Panic("[ORM] err: ", err)
+	}
+
 	// Automigrate tables
 	if cfg.Database.AutoMigrate {
-		err = migration.ServiceAutoMigration(db)
+		err = migration.ServiceAutoMigration(db, cfg)
 	}
 	log.Info("[ORM] Database connection initialized.")
 	return db, err
@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"gorm.io/gorm"
+)
+
+// DefaultOrganizationSlug is the slug of the organization the data created
+// before the organizations is moved into
+const DefaultOrganizationSlug = "default"
+
+// BackfillOrganizations moves the users, products and groups created before
+// the organizations into a default organization, the tenant scoping never
+// matches rows without one. It only runs while there are no organizations
+func BackfillOrganizations(db *gorm.DB) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var organizations, users, orphans int64
+		if err := tx.Model(&models.Organization{}).Count(&organizations).Error; err != nil {
+			return err
+		}
+		if organizations > 0 {
+			return nil
+		}
+		if err := tx.Model(&models.User{}).Count(&users).Error; err != nil {
+			return err
+		}
+		for _, m := range []interface{}{&models.Product{}, &models.Group{}} {
+			var count int64
+			if err := tx.Model(m).Where("organization_id IS NULL").Count(&count).Error; err != nil {
+				return err
+			}
+			orphans += count
+		}
+		if users == 0 && orphans == 0 {
+			return nil
+		}
+		org := &models.Organization{Name: "Default", Slug: DefaultOrganizationSlug}
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		for _, table := range []string{"products", "groups"} {
+			if err := tx.Exec("UPDATE "+table+" SET organization_id = ? WHERE organization_id IS NULL", org.ID).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Exec(`INSERT INTO organization_members (organization_id, user_id)
+			SELECT ?, id FROM users WHERE deleted_at IS NULL`, org.ID).Error
+	})
+	if err != nil {
+		logger.Error("[Migration.Jobs.BackfillOrganizations] error: ", err)
+	}
+	return err
+}
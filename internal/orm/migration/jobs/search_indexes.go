@@ -0,0 +1,26 @@
+package jobs
+
+import (
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/query"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/repositories"
+	"gorm.io/gorm"
+)
+
+// CreateSearchIndexes creates the GIN indexes of the full-text searchable
+// fields, on the expressions the Match filters search
+func CreateSearchIndexes(db *gorm.DB) error {
+	tables := map[string]query.FilterFields{
+		"products": repositories.ProductFilterFields,
+		"users":    repositories.UserFilterFields,
+	}
+	for table, fields := range tables {
+		for _, statement := range fields.SearchIndexes(table) {
+			if err := db.Exec(statement).Error; err != nil {
+				logger.Error("[Migration.Jobs.CreateSearchIndexes] error: ", err)
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package jobs
+
+import (
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/internal/rbac"
+	"gorm.io/gorm"
+)
+
+// SyncRBAC reconciles the roles, permissions and grants with the policy file
+func SyncRBAC(db *gorm.DB, policyFile string) error {
+	policy, err := rbac.LoadPolicy(policyFile)
+	if err != nil {
+		logger.Error("[Migration.Jobs.SyncRBAC] error: ", err)
+		return err
+	}
+	plan, err := rbac.Sync(db, policy, false)
+	if err != nil {
+		logger.Error("[Migration.Jobs.SyncRBAC] error: ", err)
+		return err
+	}
+	logger.Info("[Migration.Jobs.SyncRBAC] ", plan)
+	return nil
+}
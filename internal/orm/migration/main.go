@@ -6,6 +6,7 @@ import (
 	log "github.com/txbrown/gqlgen-api-starter/internal/logger"
 	"github.com/txbrown/gqlgen-api-starter/internal/orm/migration/jobs"
 	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
 	"gorm.io/gorm"
 )
 
@@ -19,6 +20,12 @@ func migrateSchema(db *gorm.DB) error {
 		&models.UserAPIKey{},
 		&models.User{},
 		&models.Product{},
+		&models.Organization{},
+		&models.OrganizationMember{},
+		&models.AuditEntry{},
+		&models.Group{},
+		&models.PersistedQuery{},
+		&models.File{},
 	}
 
 	err := db.AutoMigrate(dbModels...)
@@ -27,7 +34,7 @@ func migrateSchema(db *gorm.DB) error {
 }
 
 // ServiceAutoMigration migrates all the tables and modifications to the connected source
-func ServiceAutoMigration(db *gorm.DB) error {
+func ServiceAutoMigration(db *gorm.DB, cfg *utils.ServerConfig) error {
 	// Keep a list of migrations here
 	log.Info("[Migration.InitSchema] Initializing database schema")
 	switch db.Dialector.Name() {
@@ -39,8 +46,18 @@ func ServiceAutoMigration(db *gorm.DB) error {
 	if err := migrateSchema(db); err != nil {
 		return fmt.Errorf("[Migration.InitSchema]: %v", err)
 	}
+	if db.Dialector.Name() == "postgres" {
+		if err := jobs.CreateSearchIndexes(db); err != nil {
+			return fmt.Errorf("[Migration.InitSchema]: %v", err)
+		}
+	}
 	// Add more jobs, etc here
-	jobs.SeedRBAC(db)
+	if err := jobs.BackfillOrganizations(db); err != nil {
+		return fmt.Errorf("[Migration.InitSchema]: %v", err)
+	}
+	if err := jobs.SyncRBAC(db, cfg.RBAC.PolicyFile); err != nil {
+		return fmt.Errorf("[Migration.InitSchema]: %v", err)
+	}
 	// TODO: fix seed users
 	// jobs.SeedUsers(db)
 	return nil
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrAuditAppendOnly audit entries can't be changed once recorded
+var ErrAuditAppendOnly = errors.New("audit entries are append-only")
+
+// AuditEntry records a mutation or an auth event, who did it and its outcome
+type AuditEntry struct {
+	ID             int64      `gorm:"primary_key,auto_increment"`
+	CreatedAt      *time.Time `gorm:"index;not null;default:current_timestamp"`
+	Event          string     `gorm:"not null;index"`
+	ActorID        *uuid.UUID `gorm:"type:uuid;index"`
+	ImpersonatorID *uuid.UUID `gorm:"type:uuid;index"`
+	OrganizationID *uuid.UUID `gorm:"type:uuid;index"`
+	Operation      string     `gorm:"index"`
+	Variables      *string    `gorm:"type:jsonb"`
+	TargetEntity   string     `gorm:"index"`
+	TargetID       string     `gorm:"index"`
+	Changes        *string    `gorm:"type:jsonb"`
+	IP             string
+	Outcome        string `gorm:"not null;index"`
+	Error          string `gorm:"size:1024"`
+}
+
+// BeforeUpdate hook for AuditEntry
+func (a *AuditEntry) BeforeUpdate(db *gorm.DB) error {
+	return ErrAuditAppendOnly
+}
+
+// BeforeDelete hook for AuditEntry
+func (a *AuditEntry) BeforeDelete(db *gorm.DB) error {
+	return ErrAuditAppendOnly
+}
+
+// TenantCondition organizations only see the entries recorded inside of them
+func (a *AuditEntry) TenantCondition(table string, organizationID uuid.UUID) clause.Expression {
+	return clause.Eq{Column: clause.Column{Table: table, Name: "organization_id"}, Value: organizationID}
+}
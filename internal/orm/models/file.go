@@ -0,0 +1,15 @@
+package models
+
+import "github.com/gofrs/uuid"
+
+// File an uploaded file, its bytes are kept by the storage driver under the
+// storage key
+type File struct {
+	BaseModelSoftDelete
+	OwnerID     uuid.UUID `gorm:"type:uuid;not null;index"`
+	Name        string    `gorm:"not null"`
+	ContentType string    `gorm:"not null"`
+	Size        int64     `gorm:"not null"`
+	Checksum    string    `gorm:"size:64;not null"` // Hex sha256 of the content
+	StorageKey  string    `gorm:"not null;uniqueIndex"`
+}
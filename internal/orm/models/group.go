@@ -0,0 +1,41 @@
+package models
+
+import (
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// Group grants its roles and permissions to its members. A group nested in a
+// parent group also grants the parent's roles and permissions
+type Group struct {
+	BaseModelSoftDelete
+	OrganizationID *uuid.UUID   `gorm:"type:uuid;index"`
+	Name           string       `gorm:"not null"`
+	Description    string       `gorm:"size:1024"`
+	ParentID       *uuid.UUID   `gorm:"type:uuid;index"`
+	Parent         *Group       `gorm:"association_autocreate:false;association_autoupdate:false"`
+	Roles          []Role       `gorm:"many2many:group_roles;association_autocreate:false;association_autoupdate:false"`
+	Permissions    []Permission `gorm:"many2many:group_permissions;association_autocreate:false;association_autoupdate:false"`
+	Members        []User       `gorm:"many2many:group_members;association_autocreate:false;association_autoupdate:false"`
+}
+
+// TenantCondition groups belong to the organization they were created in
+func (g *Group) TenantCondition(table string, organizationID uuid.UUID) clause.Expression {
+	return clause.Eq{Column: clause.Column{Table: table, Name: "organization_id"}, Value: organizationID}
+}
+
+// SetOrganizationID assigns the group to the organization
+func (g *Group) SetOrganizationID(organizationID uuid.UUID) {
+	if g.OrganizationID == nil {
+		g.OrganizationID = &organizationID
+	}
+}
+
+// MemberIDs returns the ids of the group's members
+func (g *Group) MemberIDs() []string {
+	ids := []string{}
+	for _, m := range g.Members {
+		ids = append(ids, m.ID.String())
+	}
+	return ids
+}
@@ -1,7 +1,26 @@
 package models
 
+import (
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm/clause"
+)
+
 type Product struct {
 	BaseModelSoftDelete
-	Name  string
-	Price float64
+	OrganizationID *uuid.UUID `gorm:"type:uuid;index"`
+	Name           string
+	Description    *string `gorm:"size:4096"`
+	Price          float64
+}
+
+// TenantCondition products belong to the organization they were created in
+func (p *Product) TenantCondition(table string, organizationID uuid.UUID) clause.Expression {
+	return clause.Eq{Column: clause.Column{Table: table, Name: "organization_id"}, Value: organizationID}
+}
+
+// SetOrganizationID assigns the product to the organization
+func (p *Product) SetOrganizationID(organizationID uuid.UUID) {
+	if p.OrganizationID == nil {
+		p.OrganizationID = &organizationID
+	}
 }
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Organization is a tenant, the customer that owns its members and data
+type Organization struct {
+	BaseModelSoftDelete
+	Name    string               `gorm:"not null"`
+	Slug    string               `gorm:"not null;uniqueIndex"`
+	Members []OrganizationMember `gorm:"association_autocreate:false;association_autoupdate:false"`
+}
+
+// OrganizationMember is the membership of a user in an organization, with the
+// roles the user holds inside of it
+type OrganizationMember struct {
+	BaseModelSeq
+	OrganizationID uuid.UUID    `gorm:"type:uuid;not null;uniqueIndex:idx_organization_member"`
+	Organization   Organization `gorm:"association_autocreate:false;association_autoupdate:false"`
+	UserID         uuid.UUID    `gorm:"type:uuid;not null;uniqueIndex:idx_organization_member"`
+	User           User         `gorm:"association_autocreate:false;association_autoupdate:false"`
+	Roles          []Role       `gorm:"many2many:organization_member_roles;association_autocreate:false;association_autoupdate:false"`
+}
+
+// TenantScoped is implemented by the models whose rows belong to a tenant, the
+// condition filters the queries made on behalf of the organization
+type TenantScoped interface {
+	TenantCondition(table string, organizationID uuid.UUID) clause.Expression
+}
+
+// TenantOwned models get the organization assigned before they are created
+type TenantOwned interface {
+	SetOrganizationID(organizationID uuid.UUID)
+}
+
+// TenantMember models join the organization after they are created
+type TenantMember interface {
+	JoinOrganization(tx *gorm.DB, organizationID uuid.UUID) error
+}
+
+// TenantCondition an organization only sees itself
+func (o *Organization) TenantCondition(table string, organizationID uuid.UUID) clause.Expression {
+	return clause.Eq{Column: clause.Column{Table: table, Name: "id"}, Value: organizationID}
+}
+
+// TenantCondition members of an organization only see its memberships
+func (m *OrganizationMember) TenantCondition(table string, organizationID uuid.UUID) clause.Expression {
+	return clause.Eq{Column: clause.Column{Table: table, Name: "organization_id"}, Value: organizationID}
+}
+
+// SetOrganizationID memberships created inside a tenant belong to it
+func (m *OrganizationMember) SetOrganizationID(organizationID uuid.UUID) {
+	m.OrganizationID = organizationID
+}
+
+// Permissions returns the permissions granted by the member's roles
+func (m *OrganizationMember) Permissions() []Permission {
+	permissions := []Permission{}
+	for _, r := range m.Roles {
+		permissions = append(permissions, r.Permissions...)
+	}
+	return permissions
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// PersistedQuery a GraphQL document stored by the sha256 hash of its text,
+// either registered by a client through automatic persisted queries or
+// imported from a client build's manifest, which makes it trusted
+type PersistedQuery struct {
+	Hash      string     `gorm:"primary_key;size:64"`
+	Query     string     `gorm:"type:text;not null"`
+	Name      *string    // Operation name, from the manifest
+	Client    *string    `gorm:"index"` // Client build the manifest came from
+	Trusted   bool       `gorm:"not null;default:false;index"`
+	CreatedAt *time.Time `gorm:"index;not null;default:current_timestamp"`
+}
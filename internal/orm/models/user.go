@@ -10,6 +10,7 @@ import (
 	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ## Entity definitions
@@ -29,8 +30,11 @@ type User struct {
 	UserProfiles        []UserProfile `gorm:"association_autocreate:false;association_autoupdate:false"`
 	Roles               []Role        `gorm:"many2many:user_roles;association_autocreate:false;association_autoupdate:false"`
 	Permissions         []Permission  `gorm:"many2many:user_permissions;association_autocreate:false;association_autoupdate:false"`
-	CreatedBy           *User         `gorm:"association_autoupdate:false;association_autocreate:false;foreignKey:id"`
-	UpdatedBy           *User         `gorm:"association_autoupdate:false;association_autocreate:false;foreignKey:id"`
+	CreatedByID         *uuid.UUID    `gorm:"type:uuid;index"`
+	UpdatedByID         *uuid.UUID    `gorm:"type:uuid;index"`
+	// Not persisted, the organization the user is acting in. Set by the auth
+	// middleware from the token's org claim
+	ActiveOrganizationID *uuid.UUID `gorm:"-"`
 }
 
 // UserProfile saves all the related OAuth Profiles
@@ -45,11 +49,11 @@ type UserProfile struct {
 	NickName       string
 	FirstName      string
 	LastName       string
-	Location       string `gorm:"size:512"`
-	AvatarURL      string `gorm:"size:1024"`
-	Description    string `gorm:"size:1024"`
-	CreatedBy      *User  `gorm:"association_autoupdate:false;association_autocreate:false;foreignKey:user_id"`
-	UpdatedBy      *User  `gorm:"association_autoupdate:false;association_autocreate:false;foreignKey:user_id"`
+	Location       string     `gorm:"size:512"`
+	AvatarURL      string     `gorm:"size:1024"`
+	Description    string     `gorm:"size:1024"`
+	CreatedByID    *uuid.UUID `gorm:"type:uuid;index"`
+	UpdatedByID    *uuid.UUID `gorm:"type:uuid;index"`
 }
 
 // UserAPIKey generated api keys for the users
@@ -60,6 +64,9 @@ type UserAPIKey struct {
 	UserID      uuid.UUID    `gorm:"not null;index"`
 	APIKey      string       `gorm:"size:128;unique_index"`
 	Permissions []Permission `gorm:"many2many:user_api_key_permissions;association_autocreate:false;association_autoupdate:false"`
+	// Query complexity budget of the requests made with the key, the server's
+	// default when null
+	ComplexityLimit *int
 }
 
 // UserRole relation between an user and its roles
@@ -138,6 +145,24 @@ func (k *UserAPIKey) BeforeSave(db *gorm.DB) error {
 	return nil
 }
 
+// ## Tenancy
+
+// TenantCondition users of an organization are its members
+func (u *User) TenantCondition(table string, organizationID uuid.UUID) clause.Expression {
+	return clause.Expr{
+		SQL:  "? IN (SELECT user_id FROM organization_members WHERE organization_id = ?)",
+		Vars: []interface{}{clause.Column{Table: table, Name: "id"}, organizationID},
+	}
+}
+
+// JoinOrganization users created inside a tenant become its members
+func (u *User) JoinOrganization(tx *gorm.DB, organizationID uuid.UUID) error {
+	return tx.Session(&gorm.Session{NewDB: true}).Create(&OrganizationMember{
+		OrganizationID: organizationID,
+		UserID:         u.ID,
+	}).Error
+}
+
 // ## Helper functions
 
 // HasRole verifies if user possesses a role
@@ -150,11 +175,27 @@ func (u *User) HasRole(roleID int) (bool, error) {
 	return false, fmt.Errorf("The user has no [%d] roleID", roleID)
 }
 
-// HasPermission verifies if user has a specific permission
+// HasRoleName verifies if user possesses a role by its name
+func (u *User) HasRoleName(name string) bool {
+	for _, r := range u.Roles {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPlatformAdmin platform admins can act across all the organizations
+func (u *User) IsPlatformAdmin() bool {
+	return u.HasRoleName(consts.PlatformAdminRole)
+}
+
+// HasPermission verifies if user has a specific permission, its own or one
+// of its roles'
 func (u *User) HasPermission(permission string, entity string) (bool, error) {
 	tag := fmt.Sprintf(permission, consts.GetTableName(entity))
-	for _, r := range u.Permissions {
-		if r.Tag == tag {
+	for _, p := range u.grantedPermissions() {
+		if p.Tag == tag {
 			return true, nil
 		}
 	}
@@ -169,7 +210,7 @@ func (u *User) HasPermissionBool(permission string, entity string) bool {
 
 // HasPermissionTag verifies if user has a specific permission tag
 func (u *User) HasPermissionTag(tag string) (bool, error) {
-	for _, r := range u.Permissions {
+	for _, r := range u.grantedPermissions() {
 		if r.Tag == tag {
 			return true, nil
 		}
@@ -177,6 +218,40 @@ func (u *User) HasPermissionTag(tag string) (bool, error) {
 	return false, fmt.Errorf("The user has no [%s] permission", tag)
 }
 
+// RoleNames returns the names of the user's roles
+func (u *User) RoleNames() []string {
+	names := []string{}
+	for _, r := range u.Roles {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+// PermissionTags returns the user's effective permission tags, the ones of
+// its roles and of the active organization included, without duplicates
+func (u *User) PermissionTags() []string {
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, p := range u.grantedPermissions() {
+		if !seen[p.Tag] {
+			seen[p.Tag] = true
+			tags = append(tags, p.Tag)
+		}
+	}
+	return tags
+}
+
+// grantedPermissions returns the user's own permissions followed by the ones
+// its roles grant, the roles are evaluated when checked so role changes apply
+// without copying their permissions to the user
+func (u *User) grantedPermissions() []Permission {
+	permissions := append([]Permission{}, u.Permissions...)
+	for _, r := range u.Roles {
+		permissions = append(permissions, r.Permissions...)
+	}
+	return permissions
+}
+
 // GetDisplayName returns the displayName if not nil, or the first + last name
 func (u *User) GetDisplayName() string {
 	displayName := ""
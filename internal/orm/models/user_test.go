@@ -0,0 +1,31 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
+)
+
+func TestHasPermissionEvaluatesTheRoles(t *testing.T) {
+	u := &models.User{
+		Roles: []models.Role{{
+			Name:        "user",
+			Permissions: []models.Permission{{Tag: "list:products"}},
+		}},
+		Permissions: []models.Permission{{Tag: "upload:files"}},
+	}
+
+	if !u.HasPermissionBool(consts.Permissions.List, consts.EntityNames.Products) {
+		t.Error("the role's permission isn't granted")
+	}
+	if ok, _ := u.HasPermissionTag("upload:files"); !ok {
+		t.Error("the user's own permission isn't granted")
+	}
+	if u.HasPermissionBool(consts.Permissions.Delete, consts.EntityNames.Products) {
+		t.Error("a permission of neither is granted")
+	}
+	if tags := u.PermissionTags(); len(tags) != 2 || tags[0] != "upload:files" || tags[1] != "list:products" {
+		t.Errorf("tags = %v, want [upload:files list:products]", tags)
+	}
+}
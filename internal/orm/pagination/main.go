@@ -0,0 +1,229 @@
+// Package pagination implements Relay style keyset pagination. Records are
+// ordered by a sort key plus their id, and the opaque cursors carry both so a
+// page starts exactly after the previous one regardless of inserts or deletes
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// DefaultPageSize is used when neither first nor last are given
+	DefaultPageSize = 50
+	// MaxPageSize bounds first and last
+	MaxPageSize = 100
+)
+
+var (
+	// ErrInvalidCursor the cursor can't be decoded or belongs to another ordering
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidPageSize first or last are negative
+	ErrInvalidPageSize = errors.New("first and last must be positive")
+	// ErrFirstAndLast first and last can't be combined
+	ErrFirstAndLast = errors.New("first and last can't be used together")
+	// ErrInvalidOrder the ordering isn't one of the sortable fields or
+	// directions
+	ErrInvalidOrder = errors.New("invalid ordering")
+)
+
+// Args are the Relay connection arguments with the ordering
+type Args struct {
+	First     *int
+	After     *string
+	Last      *int
+	Before    *string
+	OrderBy   string
+	Direction string
+}
+
+// Cursor locates a record in an ordering
+type Cursor struct {
+	Column string      `json:"c"`
+	Key    interface{} `json:"k"`
+	ID     string      `json:"id"`
+}
+
+// PageInfo is the Relay page info of a fetched page
+type PageInfo struct {
+	HasNextPage     bool
+	HasPreviousPage bool
+	StartCursor     *string
+	EndCursor       *string
+}
+
+// Page is a fetched page, Cursors are in the same order as the records
+type Page struct {
+	Cursors []string
+	Info    PageInfo
+}
+
+// Encode returns the opaque form of the cursor
+func Encode(c Cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Decode parses an opaque cursor
+func Decode(s string) (Cursor, error) {
+	c := Cursor{}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == "" {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// Validate normalizes the ordering, which must be one of the sortable columns
+func (a *Args) Validate(sortable ...string) error {
+	a.OrderBy = utils.ToSnakeCase(a.OrderBy)
+	if a.OrderBy == "" {
+		a.OrderBy = "id"
+	}
+	allowed := false
+	for _, column := range sortable {
+		if column == a.OrderBy {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w, can't order by [%s], sortable fields are: %s", ErrInvalidOrder, a.OrderBy, strings.Join(sortable, ", "))
+	}
+	switch strings.ToUpper(a.Direction) {
+	case "", "ASC":
+		a.Direction = "ASC"
+	case "DESC":
+		a.Direction = "DESC"
+	default:
+		return fmt.Errorf("%w, invalid sort direction [%s]", ErrInvalidOrder, a.Direction)
+	}
+	if a.First != nil && a.Last != nil {
+		return ErrFirstAndLast
+	}
+	if (a.First != nil && *a.First < 0) || (a.Last != nil && *a.Last < 0) {
+		return ErrInvalidPageSize
+	}
+	return nil
+}
+
+// backward pages are fetched in the reverse ordering, then flipped
+func (a Args) backward() bool {
+	return a.Last != nil || (a.First == nil && a.Before != nil)
+}
+
+func (a Args) size() int {
+	n := DefaultPageSize
+	if a.First != nil {
+		n = *a.First
+	} else if a.Last != nil {
+		n = *a.Last
+	}
+	if n > MaxPageSize {
+		n = MaxPageSize
+	}
+	return n
+}
+
+// Fetch runs the query for the page into dest, a pointer to a slice of
+// records. The query's conditions must already be grouped, so the keyset
+// conditions are and-ed to all of them
+func Fetch(tx *gorm.DB, args Args, dest interface{}) (*Page, error) {
+	backward := args.backward()
+	direction := args.Direction
+	if backward {
+		direction = flip(direction)
+	}
+
+	for _, c := range []struct {
+		cursor *string
+		after  bool
+	}{{args.After, true}, {args.Before, false}} {
+		if c.cursor == nil {
+			continue
+		}
+		cursor, err := Decode(*c.cursor)
+		if err != nil || cursor.Column != args.OrderBy {
+			return nil, ErrInvalidCursor
+		}
+		tx = tx.Where(keyset(args.OrderBy, args.Direction, c.after, cursor))
+	}
+
+	size := args.size()
+	tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Name: args.OrderBy}, Desc: direction == "DESC"})
+	if args.OrderBy != "id" {
+		tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: direction == "DESC"})
+	}
+	result := tx.Limit(size + 1).Find(dest)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	records := reflect.ValueOf(dest).Elem()
+	more := records.Len() > size
+	if more {
+		records.Set(records.Slice(0, size))
+	}
+	if backward {
+		for i, j := 0, records.Len()-1; i < j; i, j = i+1, j-1 {
+			a, b := records.Index(i).Interface(), records.Index(j).Interface()
+			records.Index(i).Set(reflect.ValueOf(b))
+			records.Index(j).Set(reflect.ValueOf(a))
+		}
+	}
+
+	page := &Page{Cursors: make([]string, records.Len())}
+	key := result.Statement.Schema.LookUpField(args.OrderBy)
+	id := result.Statement.Schema.LookUpField("id")
+	for i := range page.Cursors {
+		rv := reflect.Indirect(records.Index(i))
+		k, _ := key.ValueOf(rv)
+		v, _ := id.ValueOf(rv)
+		page.Cursors[i] = Encode(Cursor{Column: args.OrderBy, Key: k, ID: fmt.Sprint(v)})
+	}
+	if n := len(page.Cursors); n > 0 {
+		page.Info.StartCursor = &page.Cursors[0]
+		page.Info.EndCursor = &page.Cursors[n-1]
+	}
+	if backward {
+		page.Info.HasPreviousPage = more
+		page.Info.HasNextPage = args.Before != nil
+	} else {
+		page.Info.HasNextPage = more
+		page.Info.HasPreviousPage = args.After != nil
+	}
+	return page, nil
+}
+
+// keyset is the condition of the records after, or before, the cursor
+func keyset(column string, direction string, after bool, c Cursor) clause.Expression {
+	op := ">"
+	if (direction == "DESC") == after {
+		op = "<"
+	}
+	if column == "id" {
+		return clause.Expr{SQL: "? " + op + " ?", Vars: []interface{}{clause.Column{Name: "id"}, c.ID}}
+	}
+	return clause.Expr{
+		SQL:  "(?, ?) " + op + " (?, ?)",
+		Vars: []interface{}{clause.Column{Name: column}, clause.Column{Name: "id"}, c.Key, c.ID},
+	}
+}
+
+func flip(direction string) string {
+	if direction == "DESC" {
+		return "ASC"
+	}
+	return "DESC"
+}
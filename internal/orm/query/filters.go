@@ -0,0 +1,367 @@
+// Package query builds the conditions and the orderings of the lists from the
+// clients' filters and sorts, checked against the fields each entity declares
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+	"gorm.io/gorm/clause"
+)
+
+// MaxFilterDepth bounds the nesting of the filter groups
+const MaxFilterDepth = 8
+
+// languagePattern matches the names of the text search configurations, they
+// are written into the SQL
+var languagePattern = regexp.MustCompile(`^[a-z_]+$`)
+
+// ErrInvalidFilter the filter uses a field, an operation or a value the entity
+// doesn't accept
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// ColumnType is the type the values compared with a column are checked against
+type ColumnType int
+
+const (
+	// ColumnString text columns
+	ColumnString ColumnType = iota
+	// ColumnInt integer columns
+	ColumnInt
+	// ColumnFloat numeric columns
+	ColumnFloat
+	// ColumnBool boolean columns
+	ColumnBool
+	// ColumnTime timestamp columns, values are RFC 3339 strings
+	ColumnTime
+	// ColumnUUID uuid columns
+	ColumnUUID
+)
+
+// Operation sets of the filter fields
+var (
+	EqualityOps = []model.OperationType{
+		model.OperationTypeEquals, model.OperationTypeNotEquals,
+		model.OperationTypeIn, model.OperationTypeNotIn,
+	}
+	NullOps = []model.OperationType{
+		model.OperationTypeIsNull, model.OperationTypeIsNotNull,
+	}
+	RangeOps = append(EqualityOps,
+		model.OperationTypeLessThan, model.OperationTypeLessThanEqual,
+		model.OperationTypeGreaterThan, model.OperationTypeGreaterThanEqual,
+		model.OperationTypeBetween,
+	)
+	TextOps = append(EqualityOps,
+		model.OperationTypeLike, model.OperationTypeILike, model.OperationTypeNotLike,
+	)
+)
+
+// FilterField is a column the clients can filter on, with the operations
+// allowed on it. Language is the text search configuration of the fields
+// that can be matched, e.g. english
+type FilterField struct {
+	Column   string
+	Type     ColumnType
+	Ops      []model.OperationType
+	Language string
+}
+
+// Field returns the filter field of the column, allowing the operation sets
+func Field(column string, t ColumnType, ops ...[]model.OperationType) FilterField {
+	f := FilterField{Column: column, Type: t}
+	for _, o := range ops {
+		f.Ops = append(f.Ops, o...)
+	}
+	return f
+}
+
+// Searchable allows the Match operation on the field, a full-text search of
+// the column parsed with websearch_to_tsquery in the language's configuration
+func (f FilterField) Searchable(language string) FilterField {
+	if !languagePattern.MatchString(language) {
+		panic("invalid text search configuration: " + language)
+	}
+	f.Ops = append(append([]model.OperationType{}, f.Ops...), model.OperationTypeMatch)
+	f.Language = language
+	return f
+}
+
+// Document is the tsvector of the searchable field, the expression of its
+// index
+func (f FilterField) Document() string {
+	return fmt.Sprintf("to_tsvector('%s'::regconfig, coalesce(%s::text, ''))", f.Language, f.Column)
+}
+
+// query is the tsquery of the search terms
+func (f FilterField) query() string {
+	return fmt.Sprintf("websearch_to_tsquery('%s'::regconfig, ?)", f.Language)
+}
+
+// FilterFields are the fields an entity can be filtered on, by their GraphQL
+// names. Fields missing from it are rejected before reaching the database
+type FilterFields map[string]FilterField
+
+// field returns the filter field of a GraphQL name, the snake case column name
+// is accepted too
+func (ff FilterFields) field(name string) (FilterField, bool) {
+	if f, ok := ff[name]; ok {
+		return f, true
+	}
+	column := utils.ToSnakeCase(name)
+	for _, f := range ff {
+		if f.Column == column {
+			return f, true
+		}
+	}
+	return FilterField{}, false
+}
+
+// condition returns the SQL condition of a single comparison
+func (ff FilterFields) condition(name string, op model.OperationType, value interface{}, values []interface{}) (clause.Expr, error) {
+	f, ok := ff.field(name)
+	if !ok {
+		return clause.Expr{}, fmt.Errorf("%w: unknown field [%s]", ErrInvalidFilter, name)
+	}
+	if !f.allows(op) {
+		return clause.Expr{}, fmt.Errorf("%w: operation [%s] is not allowed on field [%s]", ErrInvalidFilter, op, name)
+	}
+	sql := f.Column + opToSQL(op)
+
+	switch op {
+	case model.OperationTypeMatch:
+		terms, ok := value.(string)
+		if !ok || f.Language == "" {
+			return clause.Expr{}, fmt.Errorf("%w: operation [%s] needs the search terms in the field [value]", ErrInvalidFilter, op)
+		}
+		return clause.Expr{SQL: f.Document() + " @@ " + f.query(), Vars: []interface{}{terms}}, nil
+	case model.OperationTypeIsNull, model.OperationTypeIsNotNull:
+		return clause.Expr{SQL: sql}, nil
+	case model.OperationTypeBetween:
+		if len(values) != 2 {
+			return clause.Expr{}, fmt.Errorf("%w: operation [%s] needs an array with exactly two items in [values] field", ErrInvalidFilter, op)
+		}
+		from, err := f.coerce(name, values[0])
+		if err != nil {
+			return clause.Expr{}, err
+		}
+		to, err := f.coerce(name, values[1])
+		if err != nil {
+			return clause.Expr{}, err
+		}
+		return clause.Expr{SQL: sql, Vars: []interface{}{from, to}}, nil
+	case model.OperationTypeIn, model.OperationTypeNotIn:
+		if len(values) < 1 {
+			return clause.Expr{}, fmt.Errorf("%w: operation [%s] needs an array with at least 1 item on [values] field", ErrInvalidFilter, op)
+		}
+		coerced := make([]interface{}, len(values))
+		for i, v := range values {
+			c, err := f.coerce(name, v)
+			if err != nil {
+				return clause.Expr{}, err
+			}
+			coerced[i] = c
+		}
+		return clause.Expr{SQL: sql, Vars: []interface{}{coerced}}, nil
+	}
+
+	if value == nil {
+		return clause.Expr{}, fmt.Errorf("%w: operation [%s] needs the field [value] to compare", ErrInvalidFilter, op)
+	}
+	v, err := f.coerce(name, value)
+	if err != nil {
+		return clause.Expr{}, err
+	}
+	return clause.Expr{SQL: sql, Vars: []interface{}{v}}, nil
+}
+
+// group returns the SQL condition of a filter group, everything set in the
+// group is ANDed
+func (ff FilterFields) group(g *model.FilterGroup, depth int) (string, []interface{}, error) {
+	if depth > MaxFilterDepth {
+		return "", nil, fmt.Errorf("%w: groups can't be nested more than %d levels deep", ErrInvalidFilter, MaxFilterDepth)
+	}
+	parts := []string{}
+	vars := []interface{}{}
+
+	for _, c := range g.Conditions {
+		expr, err := ff.condition(c.Field, c.Op, c.Value, c.Values)
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, expr.SQL)
+		vars = append(vars, expr.Vars...)
+	}
+	for _, sub := range g.And {
+		sql, v, err := ff.group(sub, depth+1)
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, sql)
+		vars = append(vars, v...)
+	}
+	if len(g.Or) > 0 {
+		alternatives := make([]string, len(g.Or))
+		for i, sub := range g.Or {
+			sql, v, err := ff.group(sub, depth+1)
+			if err != nil {
+				return "", nil, err
+			}
+			alternatives[i] = sql
+			vars = append(vars, v...)
+		}
+		parts = append(parts, "("+strings.Join(alternatives, " OR ")+")")
+	}
+	if g.Not != nil {
+		sql, v, err := ff.group(g.Not, depth+1)
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, "NOT "+sql)
+		vars = append(vars, v...)
+	}
+
+	if len(parts) == 0 {
+		return "", nil, fmt.Errorf("%w: empty filter group", ErrInvalidFilter)
+	}
+	return "(" + strings.Join(parts, " AND ") + ")", vars, nil
+}
+
+// Where returns the SQL condition of the filter group
+func (ff FilterFields) Where(g *model.FilterGroup) (clause.Expr, error) {
+	sql, vars, err := ff.group(g, 1)
+	if err != nil {
+		return clause.Expr{}, err
+	}
+	return clause.Expr{SQL: sql, Vars: vars}, nil
+}
+
+// Relevance returns the sum of the ranks of the filters' and the group's
+// full-text searches, empty when they don't search. The negated groups
+// don't count
+func (ff FilterFields) Relevance(filters []*model.QueryFilter, where *model.FilterGroup) clause.Expr {
+	ranks := []string{}
+	vars := []interface{}{}
+	rank := func(name string, op model.OperationType, value interface{}) {
+		f, ok := ff.field(name)
+		terms, isString := value.(string)
+		if op != model.OperationTypeMatch || !ok || f.Language == "" || !isString {
+			return
+		}
+		ranks = append(ranks, "ts_rank("+f.Document()+", "+f.query()+")")
+		vars = append(vars, terms)
+	}
+	for _, f := range filters {
+		if f != nil {
+			rank(f.Field, f.Op, f.Value)
+		}
+	}
+	var walk func(g *model.FilterGroup, depth int)
+	walk = func(g *model.FilterGroup, depth int) {
+		if g == nil || depth > MaxFilterDepth {
+			return
+		}
+		for _, c := range g.Conditions {
+			rank(c.Field, c.Op, c.Value)
+		}
+		for _, sub := range append(append([]*model.FilterGroup{}, g.And...), g.Or...) {
+			walk(sub, depth+1)
+		}
+	}
+	walk(where, 1)
+
+	if len(ranks) == 0 {
+		return clause.Expr{}
+	}
+	return clause.Expr{SQL: "(" + strings.Join(ranks, " + ") + ")", Vars: vars}
+}
+
+// SearchIndexes returns the statements creating the GIN indexes of the
+// table's searchable fields, on the same expressions the searches use
+func (ff FilterFields) SearchIndexes(table string) []string {
+	statements := []string{}
+	for _, f := range ff {
+		if f.Language == "" {
+			continue
+		}
+		statements = append(statements, fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_%s_search ON %s USING GIN (%s)", table, f.Column, table, f.Document()))
+	}
+	sort.Strings(statements)
+	return statements
+}
+
+func (f FilterField) allows(op model.OperationType) bool {
+	for _, o := range f.Ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// coerce checks the value against the type of the column, returning it as the
+// type the column is compared with
+func (f FilterField) coerce(name string, value interface{}) (interface{}, error) {
+	invalid := fmt.Errorf("%w: invalid value %v for field [%s]", ErrInvalidFilter, value, name)
+
+	switch f.Type {
+	case ColumnString:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	case ColumnInt:
+		switch v := value.(type) {
+		case int:
+			return int64(v), nil
+		case int64:
+			return v, nil
+		case json.Number:
+			if i, err := v.Int64(); err == nil {
+				return i, nil
+			}
+		case float64:
+			if v == math.Trunc(v) {
+				return int64(v), nil
+			}
+		}
+	case ColumnFloat:
+		switch v := value.(type) {
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		case json.Number:
+			if n, err := v.Float64(); err == nil {
+				return n, nil
+			}
+		}
+	case ColumnBool:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	case ColumnTime:
+		if s, ok := value.(string); ok {
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				return t, nil
+			}
+		}
+	case ColumnUUID:
+		if s, ok := value.(string); ok {
+			if id, err := uuid.FromString(s); err == nil {
+				return id, nil
+			}
+		}
+	}
+	return nil, invalid
+}
@@ -0,0 +1,90 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidSort the ordering uses a field or a direction the entity doesn't
+// accept
+var ErrInvalidSort = errors.New("invalid sort")
+
+// Sort is a field of an ordering. Field is the name of the entity's sort
+// field, e.g. CREATED_AT, Nulls is FIRST, LAST or empty for the database's
+// default
+type Sort struct {
+	Field string
+	Desc  bool
+	Nulls string
+}
+
+// LegacySort returns the ordering of the deprecated orderBy and sortDirection
+// arguments, orderBy is a field name like createdAt or created_at
+func LegacySort(orderBy *string, sortDirection *string) ([]Sort, error) {
+	s := Sort{Field: "ID"}
+	if orderBy != nil && *orderBy != "" {
+		s.Field = strings.ToUpper(utils.ToSnakeCase(*orderBy))
+	}
+	if sortDirection != nil {
+		switch strings.ToUpper(*sortDirection) {
+		case "ASC":
+		case "DESC":
+			s.Desc = true
+		default:
+			return nil, fmt.Errorf("%w: invalid sort direction [%s]", ErrInvalidSort, *sortDirection)
+		}
+	}
+	return []Sort{s}, nil
+}
+
+// RelevanceField is the sort field of the full-text search ranking, only
+// available when the filters search
+const RelevanceField = "RELEVANCE"
+
+// SortColumns maps the sort fields of an entity to their columns
+type SortColumns map[string]string
+
+// Order adds the ordering to the transaction, the records are ordered by id
+// last so the pages are stable. relevance is the ranking of the searches of
+// the filters, see FilterFields.Relevance
+func (sc SortColumns) Order(db *gorm.DB, sorts []Sort, relevance clause.Expr) (*gorm.DB, error) {
+	orders := []string{}
+	vars := []interface{}{}
+	byID := false
+	for _, s := range sorts {
+		// The columns come from the entity's sort columns, never from the client
+		column, ok := sc[s.Field]
+		switch {
+		case s.Field == RelevanceField:
+			if relevance.SQL == "" {
+				return db, fmt.Errorf("%w: sorting by relevance needs a Match filter", ErrInvalidSort)
+			}
+			column = relevance.SQL
+			vars = append(vars, relevance.Vars...)
+		case !ok:
+			return db, fmt.Errorf("%w: can't sort by [%s]", ErrInvalidSort, s.Field)
+		}
+		order := column
+		if s.Desc {
+			order += " DESC"
+		}
+		switch s.Nulls {
+		case "":
+		case "FIRST", "LAST":
+			order += " NULLS " + s.Nulls
+		default:
+			return db, fmt.Errorf("%w: invalid nulls ordering [%s]", ErrInvalidSort, s.Nulls)
+		}
+		orders = append(orders, order)
+		byID = byID || column == "id"
+	}
+	if !byID {
+		orders = append(orders, "id")
+	}
+	return db.Clauses(clause.OrderBy{Expression: clause.Expr{SQL: strings.Join(orders, ", "), Vars: vars, WithoutParentheses: true}}), nil
+}
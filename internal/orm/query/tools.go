@@ -0,0 +1,85 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"gorm.io/gorm"
+)
+
+// ParseFilters parses the filter and adds the where condition to the
+// transaction, the fields and operations are checked against the entity's
+func ParseFilters(db *gorm.DB, fields FilterFields, filters []*model.QueryFilter) (*gorm.DB, error) {
+	for _, f := range filters {
+		if f == nil {
+			continue
+		}
+		condition, err := fields.condition(f.Field, f.Op, f.Value, f.Values)
+		if err != nil {
+			return db, err
+		}
+		if f.LinkOperation != nil && *f.LinkOperation == model.LinkOperationTypeOr {
+			db = db.Or(condition)
+		} else {
+			db = db.Where(condition)
+		}
+	}
+	return db, db.Error
+}
+
+// GroupFilters adds the filters and the filter group to the transaction as
+// parenthesized conditions, so the conditions added after them apply to all
+// the filters
+func GroupFilters(db *gorm.DB, fields FilterFields, filters []*model.QueryFilter, where *model.FilterGroup) (*gorm.DB, error) {
+	if len(filters) > 0 {
+		group, err := ParseFilters(db.Session(&gorm.Session{NewDB: true}), fields, filters)
+		if err != nil {
+			return db, err
+		}
+		db = db.Where(group)
+	}
+	if where != nil {
+		condition, err := fields.Where(where)
+		if err != nil {
+			return db, err
+		}
+		db = db.Where(condition)
+	}
+	return db, nil
+}
+
+// CountFilters returns the number of records of the transaction's model
+// matching the filters
+func CountFilters(db *gorm.DB, fields FilterFields, filters []*model.QueryFilter, where *model.FilterGroup) (int64, error) {
+	var count int64
+	tx, err := GroupFilters(db, fields, filters, where)
+	if err != nil {
+		return 0, err
+	}
+	return count, tx.Count(&count).Error
+}
+
+func opToSQL(op model.OperationType) string {
+	return map[model.OperationType]string{
+		model.OperationTypeEquals:           " = ?",
+		model.OperationTypeNotEquals:        " != ?",
+		model.OperationTypeLessThan:         " < ?",
+		model.OperationTypeLessThanEqual:    " <= ?",
+		model.OperationTypeGreaterThan:      " > ?",
+		model.OperationTypeGreaterThanEqual: " >= ?",
+		model.OperationTypeIs:               " IS ?",
+		model.OperationTypeIsNull:           " IS NULL",
+		model.OperationTypeIsNotNull:        " IS NOT NULL",
+		model.OperationTypeIn:               " IN (?)",
+		model.OperationTypeNotIn:            " NOT IN (?)",
+		model.OperationTypeLike:             " LIKE ?",
+		model.OperationTypeILike:            " ILIKE ?",
+		model.OperationTypeNotLike:          " NOT LIKE ?",
+		model.OperationTypeBetween:          " BETWEEN ? AND ?",
+	}[op]
+}
+
+func arrayToString(a interface{}, delim string) string {
+	return strings.Trim(strings.Replace(fmt.Sprint(a), "' '", delim, -1), "[]")
+}
@@ -0,0 +1,58 @@
+package query
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultLimit is the size of the lists when no limit is given
+	DefaultLimit = 50
+	// MaxLimit bounds the size of the lists
+	MaxLimit = 100
+)
+
+// ErrInvalidLimit the limit isn't positive or the offset is negative
+var ErrInvalidLimit = errors.New("limit must be positive and offset can't be negative")
+
+// Window returns the limit and the offset of a list, the defaults for the
+// missing ones. Limits above MaxLimit are lowered to it
+func Window(limit *int, offset *int) (int, int, error) {
+	l, o := DefaultLimit, 0
+	if limit != nil {
+		l = *limit
+	}
+	if offset != nil {
+		o = *offset
+	}
+	if l <= 0 || o < 0 {
+		return 0, 0, ErrInvalidLimit
+	}
+	if l > MaxLimit {
+		l = MaxLimit
+	}
+	return l, o, nil
+}
+
+// Paged applies the limit and the offset to the statement, with the defaults
+// of Window for the missing ones. The limit is capped one above MaxLimit, the
+// lists read one more row than their size to know whether there's a next page
+func Paged(db *gorm.DB, limit *int, offset *int) (*gorm.DB, error) {
+	l, o := DefaultLimit, 0
+	if limit != nil {
+		l = *limit
+	}
+	if offset != nil {
+		o = *offset
+	}
+	// GORM leaves out a limit that isn't positive, which would read the whole
+	// table, so they are refused instead
+	if l <= 0 || o < 0 {
+		return nil, ErrInvalidLimit
+	}
+	if l > MaxLimit+1 {
+		l = MaxLimit + 1
+	}
+	return db.Offset(o).Limit(l), nil
+}
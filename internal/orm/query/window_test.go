@@ -0,0 +1,81 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func TestWindow(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	tests := []struct {
+		name   string
+		limit  *int
+		offset *int
+		want   [2]int
+		err    error
+	}{
+		{name: "defaults", want: [2]int{DefaultLimit, 0}},
+		{name: "given", limit: intPtr(10), offset: intPtr(20), want: [2]int{10, 20}},
+		{name: "above the maximum", limit: intPtr(MaxLimit + 1), want: [2]int{MaxLimit, 0}},
+		{name: "zero limit", limit: intPtr(0), err: ErrInvalidLimit},
+		{name: "negative limit", limit: intPtr(-1), err: ErrInvalidLimit},
+		{name: "negative offset", offset: intPtr(-1), err: ErrInvalidLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, offset, err := Window(tt.limit, tt.offset)
+			if err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if err == nil && [2]int{limit, offset} != tt.want {
+				t.Errorf("window = %d, %d, want %d, %d", limit, offset, tt.want[0], tt.want[1])
+			}
+		})
+	}
+}
+
+func TestPaged(t *testing.T) {
+	intPtr := func(i int) *int { return &i }
+	conn, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		limit  *int
+		offset *int
+		want   [2]int
+		err    error
+	}{
+		{name: "defaults", want: [2]int{DefaultLimit, 0}},
+		{name: "given", limit: intPtr(10), offset: intPtr(20), want: [2]int{10, 20}},
+		{name: "peek past the maximum", limit: intPtr(MaxLimit + 1), want: [2]int{MaxLimit + 1, 0}},
+		{name: "above the maximum", limit: intPtr(1000), want: [2]int{MaxLimit + 1, 0}},
+		{name: "zero limit", limit: intPtr(0), err: ErrInvalidLimit},
+		{name: "negative limit", limit: intPtr(-1), err: ErrInvalidLimit},
+		{name: "negative offset", offset: intPtr(-1), err: ErrInvalidLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paged, err := Paged(db.Session(&gorm.Session{}), tt.limit, tt.offset)
+			if err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			limit := paged.Statement.Clauses["LIMIT"].Expression.(clause.Limit)
+			if [2]int{limit.Limit, limit.Offset} != tt.want {
+				t.Errorf("window = %d, %d, want %d, %d", limit.Limit, limit.Offset, tt.want[0], tt.want[1])
+			}
+		})
+	}
+}
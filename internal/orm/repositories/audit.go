@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/query"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/tenancy"
+	"gorm.io/gorm"
+)
+
+type AuditRepository interface {
+	ForTenant(t tenancy.Tenant) AuditRepository
+	Create(i *models.AuditEntry) error
+	Search(filters []*model.QueryFilter, where *model.FilterGroup, limit *int, offset *int) ([]*models.AuditEntry, error)
+	Count(filters []*model.QueryFilter, where *model.FilterGroup) (int64, error)
+}
+
+// AuditFilterFields are the fields audit entries can be filtered on
+var AuditFilterFields = query.FilterFields{
+	"id":             query.Field("id", query.ColumnInt, query.RangeOps),
+	"event":          query.Field("event", query.ColumnString, query.TextOps),
+	"actorId":        query.Field("actor_id", query.ColumnUUID, query.EqualityOps, query.NullOps),
+	"impersonatorId": query.Field("impersonator_id", query.ColumnUUID, query.EqualityOps, query.NullOps),
+	"organizationId": query.Field("organization_id", query.ColumnUUID, query.EqualityOps, query.NullOps),
+	"operation":      query.Field("operation", query.ColumnString, query.TextOps),
+	"targetEntity":   query.Field("target_entity", query.ColumnString, query.EqualityOps),
+	"targetId":       query.Field("target_id", query.ColumnString, query.EqualityOps),
+	"ip":             query.Field("ip", query.ColumnString, query.EqualityOps),
+	"outcome":        query.Field("outcome", query.ColumnString, query.EqualityOps),
+	"createdAt":      query.Field("created_at", query.ColumnTime, query.RangeOps),
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return auditRepository{
+		db: db,
+	}
+}
+
+// ForTenant returns a copy of the repository scoped to the tenant
+func (a auditRepository) ForTenant(t tenancy.Tenant) AuditRepository {
+	return auditRepository{
+		db: tenancy.Scope(a.db, t),
+	}
+}
+
+func (a auditRepository) Create(i *models.AuditEntry) error {
+	return a.db.Create(i).Error
+}
+
+// Search returns the newest entries first
+func (a auditRepository) Search(filters []*model.QueryFilter, where *model.FilterGroup, limit *int, offset *int) ([]*models.AuditEntry, error) {
+	dbRecords := []*models.AuditEntry{}
+
+	tx, err := query.GroupFilters(a.db.Model(&models.AuditEntry{}), AuditFilterFields, filters, where)
+	if err != nil {
+		return nil, err
+	}
+	if tx, err = query.Paged(tx, limit, offset); err != nil {
+		return nil, err
+	}
+	err = tx.Order("id DESC").Find(&dbRecords).Error
+
+	return dbRecords, err
+}
+
+// Count returns the number of entries matching the filters
+func (a auditRepository) Count(filters []*model.QueryFilter, where *model.FilterGroup) (int64, error) {
+	return query.CountFilters(a.db.Model(&models.AuditEntry{}), AuditFilterFields, filters, where)
+}
@@ -0,0 +1,41 @@
+package repositories
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"gorm.io/gorm"
+)
+
+type FilesRepository interface {
+	Create(i *models.File) error
+	FindById(id uuid.UUID) (*models.File, error)
+	Delete(i *models.File) error
+}
+
+type filesRepository struct {
+	db *gorm.DB
+}
+
+func NewFilesRepository(db *gorm.DB) FilesRepository {
+	return filesRepository{
+		db: db,
+	}
+}
+
+func (f filesRepository) Create(i *models.File) error {
+	return f.db.Create(i).Error
+}
+
+func (f filesRepository) FindById(id uuid.UUID) (*models.File, error) {
+	result := &models.File{}
+
+	if err := f.db.Where("id = ?", id).First(result).Error; err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (f filesRepository) Delete(i *models.File) error {
+	return f.db.Delete(i).Error
+}
@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/query"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/tenancy"
+	"gorm.io/gorm"
+)
+
+// userGroupsCTE resolves the groups of a user in an organization, or the
+// groups outside of any organization, with their ancestors
+const userGroupsCTE = `WITH RECURSIVE user_groups(id) AS (
+	SELECT g.id FROM groups g
+	JOIN group_members gm ON gm.group_id = g.id
+	WHERE gm.user_id = ? AND g.deleted_at IS NULL
+	AND (g.organization_id IS NULL OR g.organization_id = ?)
+	UNION
+	SELECT p.id FROM groups g
+	JOIN user_groups ug ON ug.id = g.id
+	JOIN groups p ON p.id = g.parent_id AND p.deleted_at IS NULL
+)
+`
+
+type GroupsRepository interface {
+	ForTenant(t tenancy.Tenant) GroupsRepository
+	Create(i *models.Group) error
+	Update(i *models.Group) error
+	Delete(id uuid.UUID) error
+	FindById(id uuid.UUID) (*models.Group, error)
+	List(id *string, limit *int, offset *int) ([]*models.Group, error)
+	Count(id *string) (int64, error)
+	AddMember(g *models.Group, u *models.User) error
+	RemoveMember(g *models.Group, u *models.User) error
+	ReplaceGrants(g *models.Group, roles []models.Role, permissions []models.Permission) error
+	Grants(userID uuid.UUID, organizationID uuid.UUID) ([]models.Role, []models.Permission, error)
+}
+
+type groupsRepository struct {
+	db *gorm.DB
+}
+
+func NewGroupsRepository(db *gorm.DB) GroupsRepository {
+	return groupsRepository{
+		db: db,
+	}
+}
+
+// ForTenant returns a copy of the repository scoped to the tenant
+func (g groupsRepository) ForTenant(t tenancy.Tenant) GroupsRepository {
+	return groupsRepository{
+		db: tenancy.Scope(g.db, t),
+	}
+}
+
+func (g groupsRepository) Create(i *models.Group) error {
+	return g.db.Omit("Parent", "Roles", "Permissions", "Members").Create(i).Error
+}
+
+func (g groupsRepository) Update(i *models.Group) error {
+	return g.db.Omit("Parent", "Roles", "Permissions", "Members").Save(i).Error
+}
+
+// Delete soft deletes the group, its grants stop applying to its members and
+// to the members of its subgroups
+func (g groupsRepository) Delete(id uuid.UUID) error {
+	return g.db.Where("id = ?", id).Delete(&models.Group{}).Error
+}
+
+// FindById returns the group with its parent, grants and members
+func (g groupsRepository) FindById(id uuid.UUID) (*models.Group, error) {
+	result := &models.Group{}
+
+	if err := g.db.Preload("Parent").Preload("Roles").Preload("Permissions").Preload("Members").
+		Where("id = ?", id).First(result).Error; err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (g groupsRepository) List(id *string, limit *int, offset *int) ([]*models.Group, error) {
+	results := []*models.Group{}
+
+	tx := g.db.Preload("Parent").Preload("Roles").Preload("Permissions").Preload("Members")
+	if id != nil {
+		tx = tx.Where("id = ?", *id)
+	}
+	tx, err := query.Paged(tx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Order("name").Find(&results).Error
+
+	return results, err
+}
+
+func (g groupsRepository) Count(id *string) (int64, error) {
+	var count int64
+
+	tx := g.db.Model(&models.Group{})
+	if id != nil {
+		tx = tx.Where("id = ?", *id)
+	}
+
+	return count, tx.Count(&count).Error
+}
+
+func (g groupsRepository) AddMember(i *models.Group, u *models.User) error {
+	return g.db.Model(i).Association("Members").Append(u)
+}
+
+func (g groupsRepository) RemoveMember(i *models.Group, u *models.User) error {
+	return g.db.Model(i).Association("Members").Delete(u)
+}
+
+// ReplaceGrants replaces the roles and permissions of the group
+func (g groupsRepository) ReplaceGrants(i *models.Group, roles []models.Role, permissions []models.Permission) error {
+	return g.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(i).Association("Roles").Replace(roles); err != nil {
+			return err
+		}
+		return tx.Model(i).Association("Permissions").Replace(permissions)
+	})
+}
+
+// Grants returns the roles and permissions the user gets from its groups in
+// the organization, the ones of the roles included
+func (g groupsRepository) Grants(userID uuid.UUID, organizationID uuid.UUID) ([]models.Role, []models.Permission, error) {
+	roles := []models.Role{}
+	permissions := []models.Permission{}
+
+	if err := g.db.Raw(userGroupsCTE+`SELECT * FROM roles WHERE id IN (
+		SELECT role_id FROM group_roles WHERE group_id IN (SELECT id FROM user_groups))`,
+		userID, organizationID).Scan(&roles).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := g.db.Raw(userGroupsCTE+`SELECT * FROM permissions WHERE id IN (
+		SELECT permission_id FROM group_permissions WHERE group_id IN (SELECT id FROM user_groups)
+		UNION
+		SELECT rp.permission_id FROM role_permissions rp
+		JOIN group_roles gr ON gr.role_id = rp.role_id
+		WHERE gr.group_id IN (SELECT id FROM user_groups))`,
+		userID, organizationID).Scan(&permissions).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return roles, permissions, nil
+}
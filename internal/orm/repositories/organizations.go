@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/tenancy"
+	"gorm.io/gorm"
+)
+
+type OrganizationsRepository interface {
+	ForTenant(t tenancy.Tenant) OrganizationsRepository
+	Create(i *models.Organization, owner *models.OrganizationMember) error
+	FindById(id uuid.UUID) (*models.Organization, error)
+	FindByUser(userID uuid.UUID) ([]*models.Organization, error)
+	FindMember(organizationID uuid.UUID, userID uuid.UUID) (*models.OrganizationMember, error)
+	SaveMember(i *models.OrganizationMember) error
+	DeleteMember(organizationID uuid.UUID, userID uuid.UUID) error
+}
+
+type organizationsRepository struct {
+	db *gorm.DB
+}
+
+func NewOrganizationsRepository(db *gorm.DB) OrganizationsRepository {
+	return organizationsRepository{
+		db: db,
+	}
+}
+
+// ForTenant returns a copy of the repository scoped to the tenant
+func (o organizationsRepository) ForTenant(t tenancy.Tenant) OrganizationsRepository {
+	return organizationsRepository{
+		db: tenancy.Scope(o.db, t),
+	}
+}
+
+// Create creates the organization and the owner's membership with its roles
+// in one transaction, an organization is never left without its owner
+func (o organizationsRepository) Create(i *models.Organization, owner *models.OrganizationMember) error {
+	return o.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(i).Error; err != nil {
+			return err
+		}
+		owner.OrganizationID = i.ID
+		if err := tx.Omit("Roles").Create(owner).Error; err != nil {
+			return err
+		}
+		return tx.Model(owner).Association("Roles").Replace(owner.Roles)
+	})
+}
+
+func (o organizationsRepository) FindById(id uuid.UUID) (*models.Organization, error) {
+	result := &models.Organization{}
+
+	if err := o.db.Where("id = ?", id).First(result).Error; err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// FindByUser returns the organizations the user is a member of
+func (o organizationsRepository) FindByUser(userID uuid.UUID) ([]*models.Organization, error) {
+	results := []*models.Organization{}
+
+	err := o.db.
+		Where("id IN (SELECT organization_id FROM organization_members WHERE user_id = ?)", userID).
+		Order("name").Find(&results).Error
+
+	return results, err
+}
+
+// FindMember returns the membership with its roles and their permissions
+func (o organizationsRepository) FindMember(organizationID uuid.UUID, userID uuid.UUID) (*models.OrganizationMember, error) {
+	result := &models.OrganizationMember{}
+
+	if err := o.db.Preload("Roles.Permissions").Preload("Organization").Preload("User").
+		Where("organization_id = ? AND user_id = ?", organizationID, userID).
+		First(result).Error; err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// SaveMember creates or updates the membership and replaces its roles
+func (o organizationsRepository) SaveMember(i *models.OrganizationMember) error {
+	return o.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Roles").Save(i).Error; err != nil {
+			return err
+		}
+		return tx.Model(i).Association("Roles").Replace(i.Roles)
+	})
+}
+
+func (o organizationsRepository) DeleteMember(organizationID uuid.UUID, userID uuid.UUID) error {
+	return o.db.Transaction(func(tx *gorm.DB) error {
+		m := &models.OrganizationMember{}
+		if err := tx.Where("organization_id = ? AND user_id = ?", organizationID, userID).
+			First(m).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(m).Association("Roles").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(m).Error
+	})
+}
@@ -0,0 +1,43 @@
+package repositories_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofrs/uuid"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/repositories"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+)
+
+func TestOrganizationsCreateRollsBackWithoutTheOwner(t *testing.T) {
+	db, mock, err := orm.NewDBMock(utils.TestServerconf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := repositories.NewOrganizationsRepository(db)
+	id := uuid.Must(uuid.NewV4())
+	failure := errors.New("duplicate key")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "organizations"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}))
+	mock.ExpectQuery(`INSERT INTO "organization_members"`).WillReturnError(failure)
+	mock.ExpectRollback()
+
+	// the id is given, the order GORM scans the returned columns in varies
+	org := &models.Organization{Name: "Acme", Slug: "acme"}
+	org.ID = id
+	owner := &models.OrganizationMember{UserID: uuid.Must(uuid.NewV4())}
+	if err := repo.Create(org, owner); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	if owner.OrganizationID != id {
+		t.Errorf("owner organization = %v, want %v", owner.OrganizationID, id)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PersistedQueriesRepository interface {
+	FindByHash(hash string) (*models.PersistedQuery, error)
+	Create(i *models.PersistedQuery) error
+	Upsert(i []*models.PersistedQuery) error
+}
+
+type persistedQueriesRepository struct {
+	db *gorm.DB
+}
+
+func NewPersistedQueriesRepository(db *gorm.DB) PersistedQueriesRepository {
+	return persistedQueriesRepository{
+		db: db,
+	}
+}
+
+func (p persistedQueriesRepository) FindByHash(hash string) (*models.PersistedQuery, error) {
+	result := &models.PersistedQuery{}
+
+	if err := p.db.Where("hash = ?", hash).First(result).Error; err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Create stores the query, a query already stored, trusted or not, is kept as
+// it is
+func (p persistedQueriesRepository) Create(i *models.PersistedQuery) error {
+	return p.db.Clauses(clause.OnConflict{DoNothing: true}).Create(i).Error
+}
+
+// Upsert stores the queries, replacing the ones with the same hash
+func (p persistedQueriesRepository) Upsert(i []*models.PersistedQuery) error {
+	if len(i) == 0 {
+		return nil
+	}
+	return p.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "client", "trusted"}),
+	}).Create(&i).Error
+}
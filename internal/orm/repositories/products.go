@@ -1,17 +1,48 @@
 package repositories
 
 import (
+	"github.com/gofrs/uuid"
 	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
-	"github.com/txbrown/gqlgen-api-starter/internal/orm"
 	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
-	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/pagination"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/query"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/tenancy"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
 type ProductsRepository interface {
+	ForTenant(t tenancy.Tenant) ProductsRepository
 	Create(i *models.Product) error
-	Products(id *string, filters []*model.QueryFilter, limit *int, offset *int, orderBy *string, sortDirection *string) ([]*models.Product, error)
+	Update(i *models.Product) error
+	FindById(id uuid.UUID) (*models.Product, error)
+	FindByIds(ids []uuid.UUID) ([]*models.Product, error)
+	Products(id *string, filters []*model.QueryFilter, where *model.FilterGroup, limit *int, offset *int, sort []query.Sort) ([]*models.Product, error)
+	Page(filters []*model.QueryFilter, where *model.FilterGroup, args pagination.Args) ([]*models.Product, *pagination.Page, error)
+	Count(id *string, filters []*model.QueryFilter, where *model.FilterGroup) (int64, error)
+}
+
+// ProductSortKeys are the columns products can be paginated by
+var ProductSortKeys = []string{"id", "name", "price", "created_at"}
+
+// ProductSortColumns are the columns of the product sort fields
+var ProductSortColumns = query.SortColumns{
+	"ID":         "id",
+	"NAME":       "name",
+	"PRICE":      "price",
+	"CREATED_AT": "created_at",
+	"UPDATED_AT": "updated_at",
+}
+
+// ProductFilterFields are the fields products can be filtered on, the name
+// and the description are searched in english
+var ProductFilterFields = query.FilterFields{
+	"id":          query.Field("id", query.ColumnUUID, query.EqualityOps),
+	"name":        query.Field("name", query.ColumnString, query.TextOps).Searchable("english"),
+	"description": query.Field("description", query.ColumnString, query.TextOps, query.NullOps).Searchable("english"),
+	"price":       query.Field("price", query.ColumnFloat, query.RangeOps),
+	"createdAt":   query.Field("created_at", query.ColumnTime, query.RangeOps),
+	"updatedAt":   query.Field("updated_at", query.ColumnTime, query.RangeOps, query.NullOps),
 }
 
 type productsRepository struct {
@@ -19,40 +50,109 @@ type productsRepository struct {
 }
 
 func NewProductsRepository(db *gorm.DB) ProductsRepository {
-	return productsRepository{}
+	return productsRepository{
+		db: db,
+	}
+}
+
+// ForTenant returns a copy of the repository scoped to the tenant
+func (p productsRepository) ForTenant(t tenancy.Tenant) ProductsRepository {
+	return productsRepository{
+		db: tenancy.Scope(p.db, t),
+	}
 }
 
 func (p productsRepository) Create(i *models.Product) error {
 	tx := p.db.Begin()
 
-	tx = tx.Create(i).First(i)
-	if tx.Error != nil {
-		return tx.Error
+	if err := tx.Create(i).First(i).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (p productsRepository) Update(i *models.Product) error {
+	tx := p.db.Begin()
+
+	if err := tx.Model(i).Save(i).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (p productsRepository) FindById(id uuid.UUID) (*models.Product, error) {
+	result := &models.Product{}
+
+	if err := p.db.Where("id = ?", id).First(result).Error; err != nil {
+		return nil, err
 	}
 
-	tx = tx.Commit()
+	return result, nil
+}
+
+// FindByIds returns the products with any of the ids, in no particular order
+func (p productsRepository) FindByIds(ids []uuid.UUID) ([]*models.Product, error) {
+	results := []*models.Product{}
+
+	if err := p.db.Where("id IN ?", ids).Find(&results).Error; err != nil {
+		return nil, err
+	}
 
-	return tx.Error
+	return results, nil
 }
 
-func (p productsRepository) Products(id *string, filters []*model.QueryFilter, limit *int, offset *int, orderBy *string, sortDirection *string) ([]*models.Product, error) {
+func (p productsRepository) Products(id *string, filters []*model.QueryFilter, where *model.FilterGroup, limit *int, offset *int, sort []query.Sort) ([]*models.Product, error) {
 	whereID := "id = ?"
 	dbRecords := []*models.Product{}
 
-	tx := p.db.Begin().
-		Offset(*offset).Limit(*limit).Order(utils.ToSnakeCase(*orderBy) + " " + *sortDirection)
+	tx, err := query.Paged(p.db, limit, offset)
+	if err != nil {
+		return nil, err
+	}
 	if id != nil {
 		tx = tx.Where(whereID, *id)
 	}
-	if filters != nil {
-		if filtered, err := orm.ParseFilters(tx, filters); err == nil {
-			tx = filtered
-		} else {
-			return nil, err
-		}
+	tx, err = query.GroupFilters(tx, ProductFilterFields, filters, where)
+	if err == nil {
+		tx, err = ProductSortColumns.Order(tx, sort, ProductFilterFields.Relevance(filters, where))
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	tx = tx.Preload(clause.Associations).Find(&dbRecords)
 
 	return dbRecords, tx.Error
 }
+
+// Count returns the number of products matching the filters
+func (p productsRepository) Count(id *string, filters []*model.QueryFilter, where *model.FilterGroup) (int64, error) {
+	tx := p.db.Model(&models.Product{})
+	if id != nil {
+		tx = tx.Where("id = ?", *id)
+	}
+	return query.CountFilters(tx, ProductFilterFields, filters, where)
+}
+
+// Page returns a keyset paginated page of the filtered products
+func (p productsRepository) Page(filters []*model.QueryFilter, where *model.FilterGroup, args pagination.Args) ([]*models.Product, *pagination.Page, error) {
+	if err := args.Validate(ProductSortKeys...); err != nil {
+		return nil, nil, err
+	}
+	dbRecords := []*models.Product{}
+
+	tx, err := query.GroupFilters(p.db.Model(&models.Product{}), ProductFilterFields, filters, where)
+	if err != nil {
+		return nil, nil, err
+	}
+	page, err := pagination.Fetch(tx, args, &dbRecords)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return dbRecords, page, nil
+}
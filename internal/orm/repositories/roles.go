@@ -9,6 +9,8 @@ type RolesRepository interface {
 	Find(where *map[string]string) ([]*models.Role, error)
 	FirstWhere(where string) (*models.Role, error)
 	FindById(id int) (*models.Role, error)
+	FindByNames(names []string) ([]models.Role, error)
+	FindPermissionsByTags(tags []string) ([]models.Permission, error)
 	Create(i *models.Role) (int, error)
 	Update(i *models.Role) error
 	Delete(id int) error
@@ -59,6 +61,26 @@ func (l rolesRepository) FindById(id int) (*models.Role, error) {
 	return result, tx.Commit().Error
 }
 
+func (l rolesRepository) FindByNames(names []string) ([]models.Role, error) {
+	results := []models.Role{}
+
+	if err := l.db.Where("name IN ?", names).Find(&results).Error; err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (l rolesRepository) FindPermissionsByTags(tags []string) ([]models.Permission, error) {
+	results := []models.Permission{}
+
+	if err := l.db.Where("tag IN ?", tags).Find(&results).Error; err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
 func (l rolesRepository) Create(i *models.Role) (int, error) {
 	tx := l.db.Begin()
 
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"github.com/gofrs/uuid"
 	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
 	"gorm.io/gorm"
 )
@@ -9,6 +10,7 @@ type UserProfilesRepository interface {
 	Find() ([]*models.UserProfile, error)
 	FindById(id int) (*models.UserProfile, error)
 	FindByEmail(email string) (*models.UserProfile, error)
+	FindByUserIds(userIDs []uuid.UUID, limit int, offset int) ([]*models.UserProfile, error)
 	Create(i *models.UserProfile) (int, error)
 	Update(i *models.UserProfile) error
 	Delete(id int) error
@@ -37,17 +39,13 @@ func (l userProfilesRepository) Find() ([]*models.UserProfile, error) {
 }
 
 func (l userProfilesRepository) FindById(id int) (*models.UserProfile, error) {
-	tx := l.db.Begin()
+	result := &models.UserProfile{}
 
-	result := &models.UserProfile{
-		BaseModelSeq: models.BaseModelSeq{
-			ID: id,
-		},
+	if err := l.db.Model(&models.UserProfile{}).First(result, id).Error; err != nil {
+		return nil, err
 	}
 
-	tx.Model(&models.UserProfile{}).First(result)
-
-	return result, tx.Commit().Error
+	return result, nil
 }
 
 func (l userProfilesRepository) FindByEmail(email string) (*models.UserProfile, error) {
@@ -60,6 +58,23 @@ func (l userProfilesRepository) FindByEmail(email string) (*models.UserProfile,
 	return result, tx.Commit().Error
 }
 
+// FindByUserIds returns a window of each user's profiles, ordered by id, so
+// the profiles of many users are paged in a single query
+func (l userProfilesRepository) FindByUserIds(userIDs []uuid.UUID, limit int, offset int) ([]*models.UserProfile, error) {
+	results := []*models.UserProfile{}
+
+	ranked := l.db.Model(&models.UserProfile{}).
+		Select("*, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY id) AS profile_index").
+		Where("user_id IN ?", userIDs)
+	if err := l.db.Table("(?) AS user_profiles", ranked).
+		Where("profile_index > ? AND profile_index <= ?", offset, offset+limit).
+		Order("user_id, id").Find(&results).Error; err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
 func (l userProfilesRepository) Create(i *models.UserProfile) (int, error) {
 	tx := l.db.Begin()
 
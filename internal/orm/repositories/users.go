@@ -6,34 +6,82 @@ import (
 
 	"github.com/gofrs/uuid"
 	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
-	"github.com/txbrown/gqlgen-api-starter/internal/orm"
 	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
-	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/pagination"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/query"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/tenancy"
 	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
 type UsersRepository interface {
+	ForTenant(t tenancy.Tenant) UsersRepository
 	Find() ([]*models.User, error)
 	FindById(id uuid.UUID) (*models.User, error)
+	FindByIds(ids []uuid.UUID) ([]*models.User, error)
 	FindByEmail(email string) (*models.User, error)
 	Create(i *models.User) (uuid.UUID, error)
 	Update(i *models.User) error
 	Delete(id uuid.UUID) error
 	FindUserByAPIKey(apiKey string) (*models.User, error)
+	FindAPIKey(apiKey string) (*models.UserAPIKey, error)
 	FindUserByJWT(email string, provider string, userID string) (*models.User, error)
 	FindUserByExternalIdentifier(externalUserID string, provider string) (*models.User, error)
 	UpsertUserProfile(i *models.UserProfile) (int, error)
-	Search(id *string, filters []*model.QueryFilter, limit *int, offset *int, orderBy *string, sortDirection *string) ([]*models.User, error)
+	Search(id *string, filters []*model.QueryFilter, where *model.FilterGroup, limit *int, offset *int, sort []query.Sort) ([]*models.User, error)
+	Page(filters []*model.QueryFilter, where *model.FilterGroup, args pagination.Args) ([]*models.User, *pagination.Page, error)
+	Count(id *string, filters []*model.QueryFilter, where *model.FilterGroup) (int64, error)
 }
 
+// UserSortKeys are the columns users can be paginated by
+var UserSortKeys = []string{"id", "email", "created_at"}
+
+// UserSortColumns are the columns of the user sort fields
+var UserSortColumns = query.SortColumns{
+	"ID":         "id",
+	"EMAIL":      "email",
+	"NAME":       "name",
+	"FIRST_NAME": "first_name",
+	"LAST_NAME":  "last_name",
+	"NICK_NAME":  "nick_name",
+	"CREATED_AT": "created_at",
+	"UPDATED_AT": "updated_at",
+}
+
+// UserFilterFields are the fields users can be filtered on, the names and the
+// email are searched without stemming
+var UserFilterFields = query.FilterFields{
+	"id":        query.Field("id", query.ColumnUUID, query.EqualityOps),
+	"email":     query.Field("email", query.ColumnString, query.TextOps).Searchable("simple"),
+	"name":      query.Field("name", query.ColumnString, query.TextOps, query.NullOps).Searchable("simple"),
+	"firstName": query.Field("first_name", query.ColumnString, query.TextOps, query.NullOps).Searchable("simple"),
+	"lastName":  query.Field("last_name", query.ColumnString, query.TextOps, query.NullOps).Searchable("simple"),
+	"nickName":  query.Field("nick_name", query.ColumnString, query.TextOps, query.NullOps).Searchable("simple"),
+	"location":  query.Field("location", query.ColumnString, query.TextOps, query.NullOps),
+	"createdAt": query.Field("created_at", query.ColumnTime, query.RangeOps),
+	"updatedAt": query.Field("updated_at", query.ColumnTime, query.RangeOps, query.NullOps),
+}
+
+// rolePermissions preloads the user's roles with the permissions they grant,
+// which HasPermission evaluates along with the user's own
+var rolePermissions = fmt.Sprintf(consts.NestedFmt, consts.EntityNames.Roles, consts.EntityNames.Permissions)
+
 type usersRepository struct {
 	db *gorm.DB
 }
 
 func NewUsersRepository(db *gorm.DB) UsersRepository {
-	return usersRepository{}
+	return usersRepository{
+		db: db,
+	}
+}
+
+// ForTenant returns a copy of the repository scoped to the tenant
+func (l usersRepository) ForTenant(t tenancy.Tenant) UsersRepository {
+	return usersRepository{
+		db: tenancy.Scope(l.db, t),
+	}
 }
 
 func (l usersRepository) Find() ([]*models.User, error) {
@@ -48,8 +96,6 @@ func (l usersRepository) Find() ([]*models.User, error) {
 
 func (l usersRepository) FindById(id uuid.UUID) (*models.User, error) {
 	tx := l.db.Begin()
-	up := fmt.Sprintf(consts.NestedFmt, "User", consts.EntityNames.Permissions)
-	ur := fmt.Sprintf(consts.NestedFmt, "User", consts.EntityNames.Roles)
 
 	result := &models.User{
 		BaseModelSoftDelete: models.BaseModelSoftDelete{
@@ -59,17 +105,31 @@ func (l usersRepository) FindById(id uuid.UUID) (*models.User, error) {
 		},
 	}
 
-	tx.Model(&models.User{}).Preload(up).Preload(ur).First(result)
+	if err := tx.Model(&models.User{}).Preload(consts.EntityNames.Permissions).Preload(rolePermissions).First(result).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 
 	return result, tx.Commit().Error
 }
 
+// FindByIds returns the users with any of the ids, in no particular order
+func (l usersRepository) FindByIds(ids []uuid.UUID) ([]*models.User, error) {
+	results := []*models.User{}
+
+	if err := l.db.Where("id IN ?", ids).Find(&results).Error; err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
 func (l usersRepository) FindByEmail(email string) (*models.User, error) {
 	tx := l.db.Begin()
 
 	result := &models.User{}
 
-	if err := tx.Model(&models.User{}).Preload(clause.Associations).Where("email = ?", email).First(result).Commit().Error; err != nil {
+	if err := tx.Model(&models.User{}).Preload(clause.Associations).Preload(rolePermissions).Where("email = ?", email).First(result).Commit().Error; err != nil {
 		return nil, err
 	}
 
@@ -87,39 +147,60 @@ func (l usersRepository) Create(i *models.User) (uuid.UUID, error) {
 func (l usersRepository) Update(i *models.User) error {
 	tx := l.db.Begin()
 
-	tx.Session(&gorm.Session{SkipHooks: true}).Model(i).Save(i)
+	// the password is hashed by the caller, the hooks would hash it again
+	if err := tx.Session(&gorm.Session{SkipHooks: true}).Model(i).Omit("CreatedByID", clause.Associations).Save(i).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
 
 	return tx.Commit().Error
 }
 
+// Delete soft deletes the user, kept for the audit trail, and removes their
+// profiles and API keys in the same transaction
 func (l usersRepository) Delete(id uuid.UUID) error {
-	tx := l.db.Begin()
-
-	tx.Model(&models.User{}).Delete(&models.User{
-		BaseModelSoftDelete: models.BaseModelSoftDelete{
-			BaseModel: models.BaseModel{
-				ID: id,
-			},
-		},
+	return l.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", id).Delete(&models.UserProfile{}).Error; err != nil {
+			return err
+		}
+		keys := tx.Model(&models.UserAPIKey{}).Select("id").Where("user_id = ?", id)
+		if err := tx.Exec("DELETE FROM user_api_key_permissions WHERE user_api_key_id IN (?)", keys).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&models.UserAPIKey{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ?", id).Delete(&models.User{})
+		if result.Error == nil && result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return result.Error
 	})
-
-	return tx.Commit().Error
 }
 
 //FindUserByAPIKey finds the user that is related to the API key
 func (u usersRepository) FindUserByAPIKey(apiKey string) (*models.User, error) {
+	uak, err := u.FindAPIKey(apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return &uak.User, nil
+}
+
+// FindAPIKey finds the API key with the user it belongs to
+func (u usersRepository) FindAPIKey(apiKey string) (*models.UserAPIKey, error) {
 	if apiKey == "" {
 		return nil, errors.New("API key is empty")
 	}
 	uak := &models.UserAPIKey{}
 	up := fmt.Sprintf(consts.NestedFmt, "User", consts.EntityNames.Permissions)
-	ur := fmt.Sprintf(consts.NestedFmt, "User", consts.EntityNames.Roles)
+	ur := fmt.Sprintf(consts.NestedFmt, "User.Roles", consts.EntityNames.Permissions)
 	tx := u.db.Begin()
 	if err := tx.Preload("User").Preload(up).Preload(ur).
 		Where("api_key = ?", apiKey).Find(uak).Commit().Error; err != nil {
 		return nil, err
 	}
-	return &uak.User, nil
+	return uak, nil
 }
 
 // FindUserByJWT finds the user that is related to the APIKey token
@@ -130,10 +211,10 @@ func (u usersRepository) FindUserByJWT(email string, provider string, userID str
 	tx := u.db.Begin()
 	p := &models.UserProfile{}
 	up := fmt.Sprintf(consts.NestedFmt, "User", consts.EntityNames.Permissions)
-	ur := fmt.Sprintf(consts.NestedFmt, "User", consts.EntityNames.Roles)
+	ur := fmt.Sprintf(consts.NestedFmt, "User.Roles", consts.EntityNames.Permissions)
 
 	if provider == consts.Providers.DB {
-		if err := tx.Preload("User").Preload(up).Preload(ur).Preload("User.Roles.Permissions").
+		if err := tx.Preload("User").Preload(up).Preload(ur).
 			Where("email = ? AND provider = ? AND user_id = ?", email, provider, userID).
 			First(p).Commit().Error; err != nil {
 			return nil, err
@@ -157,9 +238,9 @@ func (u usersRepository) FindUserByExternalIdentifier(externalUserID string, pro
 	tx := u.db.Begin()
 	p := &models.UserProfile{}
 	up := fmt.Sprintf(consts.NestedFmt, "User", consts.EntityNames.Permissions)
-	ur := fmt.Sprintf(consts.NestedFmt, "User", consts.EntityNames.Roles)
+	ur := fmt.Sprintf(consts.NestedFmt, "User.Roles", consts.EntityNames.Permissions)
 	usp := fmt.Sprintf(consts.NestedFmt, "User", consts.EntityNames.UserProfiles)
-	if err := tx.Preload(consts.EntityNames.Users).Preload(up).Preload(ur).Preload(usp).
+	if err := tx.Preload("User").Preload(up).Preload(ur).Preload(usp).
 		Where("provider = ? AND external_user_id = ?", provider, externalUserID).
 		First(p).Commit().Error; err != nil {
 		return nil, err
@@ -175,26 +256,59 @@ func (l usersRepository) UpsertUserProfile(i *models.UserProfile) (int, error) {
 	return i.ID, tx.Commit().Error
 }
 
-func (up usersRepository) Search(id *string, filters []*model.QueryFilter, limit *int, offset *int, orderBy *string, sortDirection *string) ([]*models.User, error) {
+func (up usersRepository) Search(id *string, filters []*model.QueryFilter, where *model.FilterGroup, limit *int, offset *int, sort []query.Sort) ([]*models.User, error) {
 
 	whereID := "id = ?"
 
 	dbRecords := []*models.User{}
-	tx := up.db.Begin().
-		Offset(*offset).Limit(*limit).Order(utils.ToSnakeCase(*orderBy) + " " + *sortDirection).
-		Preload(consts.EntityNames.UserProfiles)
+	tx := up.db.Begin()
+	paged, err := query.Paged(tx, limit, offset)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	tx = paged
 	if id != nil {
 		tx = tx.Where(whereID, *id)
 	}
-	if filters != nil {
-		if filtered, err := orm.ParseFilters(tx, filters); err == nil {
-			tx = filtered
-		} else {
-			return nil, err
-		}
+	tx, err = query.GroupFilters(tx, UserFilterFields, filters, where)
+	if err == nil {
+		tx, err = UserSortColumns.Order(tx, sort, UserFilterFields.Relevance(filters, where))
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, err
 	}
 
 	tx = tx.Find(&dbRecords)
 
 	return dbRecords, tx.Commit().Error
 }
+
+// Count returns the number of users matching the filters
+func (up usersRepository) Count(id *string, filters []*model.QueryFilter, where *model.FilterGroup) (int64, error) {
+	tx := up.db.Model(&models.User{})
+	if id != nil {
+		tx = tx.Where("id = ?", *id)
+	}
+	return query.CountFilters(tx, UserFilterFields, filters, where)
+}
+
+// Page returns a keyset paginated page of the filtered users
+func (up usersRepository) Page(filters []*model.QueryFilter, where *model.FilterGroup, args pagination.Args) ([]*models.User, *pagination.Page, error) {
+	if err := args.Validate(UserSortKeys...); err != nil {
+		return nil, nil, err
+	}
+	dbRecords := []*models.User{}
+
+	tx, err := query.GroupFilters(up.db.Model(&models.User{}), UserFilterFields, filters, where)
+	if err != nil {
+		return nil, nil, err
+	}
+	page, err := pagination.Fetch(tx, args, &dbRecords)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return dbRecords, page, nil
+}
@@ -0,0 +1,90 @@
+package repositories_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofrs/uuid"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/repositories"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+)
+
+func newMockedUsers(t *testing.T) (repositories.UsersRepository, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := orm.NewDBMock(utils.TestServerconf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repositories.NewUsersRepository(db), mock
+}
+
+func TestUsersFindByIdPreloadsRolesWithTheirPermissions(t *testing.T) {
+	repo, mock := newMockedUsers(t)
+	id := uuid.Must(uuid.NewV4())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE .*"users"."id" = \$1`).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(id, "user@example.com"))
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectQuery(`SELECT \* FROM "user_permissions" WHERE "user_permissions"."user_id" = \$1`).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "permission_id"}).AddRow(id, 1))
+	mock.ExpectQuery(`SELECT \* FROM "permissions" WHERE "permissions"."id" = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tag"}).AddRow(1, "read:products"))
+	mock.ExpectQuery(`SELECT \* FROM "user_roles" WHERE "user_roles"."user_id" = \$1`).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role_id"}).AddRow(id, 2))
+	mock.ExpectQuery(`SELECT \* FROM "roles" WHERE "roles"."id" = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "user"))
+	mock.ExpectQuery(`SELECT \* FROM "role_permissions" WHERE "role_permissions"."role_id" = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"role_id", "permission_id"}).AddRow(2, 3))
+	mock.ExpectQuery(`SELECT \* FROM "permissions" WHERE "permissions"."id" = \$1`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tag"}).AddRow(3, "list:products"))
+	mock.ExpectCommit()
+
+	u, err := repo.FindById(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Email != "user@example.com" {
+		t.Errorf("email = %q, want user@example.com", u.Email)
+	}
+	if len(u.Permissions) != 1 || u.Permissions[0].Tag != "read:products" {
+		t.Errorf("permissions = %+v, want read:products", u.Permissions)
+	}
+	if len(u.Roles) != 1 || u.Roles[0].Name != "user" {
+		t.Fatalf("roles = %+v, want user", u.Roles)
+	}
+	if len(u.Roles[0].Permissions) != 1 || u.Roles[0].Permissions[0].Tag != "list:products" {
+		t.Errorf("role permissions = %+v, want list:products", u.Roles[0].Permissions)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUsersUpdateReturnsTheSaveError(t *testing.T) {
+	repo, mock := newMockedUsers(t)
+	u := &models.User{Email: "user@example.com"}
+	u.ID = uuid.Must(uuid.NewV4())
+	failure := errors.New("connection lost")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "users" SET`).WillReturnError(failure)
+	mock.ExpectRollback()
+
+	if err := repo.Update(u); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,194 @@
+// Package tenancy scopes the database access to an organization. The tenant
+// travels in the statement's context and GORM callbacks filter and assign the
+// rows of the models implementing the models.Tenant* interfaces
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/gofrs/uuid"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNoOrganization the caller has no active organization to read or create
+// the organization's rows in
+var ErrNoOrganization = errors.New("no active organization")
+
+type contextKey struct{}
+
+// Tenant is the organization the database is accessed for
+type Tenant struct {
+	OrganizationID uuid.UUID
+	// CrossTenant disables the scoping, only for platform admins
+	CrossTenant bool
+}
+
+// ForUser returns the tenant a user acts in. Users without an active
+// organization get no organization, their reads and creates of the tenant's
+// rows fail with ErrNoOrganization, unless they are platform admins
+func ForUser(u *models.User) Tenant {
+	if u == nil {
+		return Tenant{}
+	}
+	if u.ActiveOrganizationID != nil {
+		return Tenant{OrganizationID: *u.ActiveOrganizationID}
+	}
+	if u.IsPlatformAdmin() {
+		return Tenant{CrossTenant: true}
+	}
+	return Tenant{}
+}
+
+// NewContext returns a context carrying the tenant
+func NewContext(ctx context.Context, t Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant of the context, if any
+func FromContext(ctx context.Context) (Tenant, bool) {
+	if ctx == nil {
+		return Tenant{}, false
+	}
+	t, ok := ctx.Value(contextKey{}).(Tenant)
+	return t, ok
+}
+
+// Scope returns a db session whose statements are scoped to the tenant
+func Scope(db *gorm.DB, t Tenant) *gorm.DB {
+	return db.WithContext(NewContext(context.Background(), t))
+}
+
+// RegisterCallbacks installs the tenancy callbacks on the db
+func RegisterCallbacks(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Query().Before("gorm:query").Register("tenancy:scope", scope); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("tenancy:scope", scope); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("tenancy:scope", scope); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("tenancy:scope", scope); err != nil {
+		return err
+	}
+	if err := cb.Create().Before("gorm:create").Register("tenancy:assign", assign); err != nil {
+		return err
+	}
+	return cb.Create().After("gorm:create").Register("tenancy:join", join)
+}
+
+func tenantOf(db *gorm.DB) (Tenant, bool) {
+	t, ok := FromContext(db.Statement.Context)
+	if !ok || t.CrossTenant || db.Statement.Schema == nil {
+		return t, false
+	}
+	return t, true
+}
+
+// refuse fails the statement when the tenant has no organization, instead of
+// scoping it to, or stamping its rows with, the nil organization
+func refuse(db *gorm.DB, t Tenant) bool {
+	if t.OrganizationID != uuid.Nil {
+		return false
+	}
+	db.AddError(ErrNoOrganization)
+	return true
+}
+
+// model returns a new instance of the statement's model, to check which of the
+// tenancy interfaces it implements
+func model(db *gorm.DB) interface{} {
+	return reflect.New(db.Statement.Schema.ModelType).Interface()
+}
+
+func scope(db *gorm.DB) {
+	t, ok := tenantOf(db)
+	if !ok {
+		return
+	}
+	if m, ok := model(db).(models.TenantScoped); ok {
+		if refuse(db, t) {
+			return
+		}
+		groupConditions(db.Statement)
+		db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+			m.TenantCondition(db.Statement.Table, t.OrganizationID),
+		}})
+	}
+}
+
+// groupConditions wraps the statement's conditions in parentheses, so the
+// tenant condition applies to all of them and not only to the last one of a
+// chain of ORs
+func groupConditions(stmt *gorm.Statement) {
+	c, ok := stmt.Clauses["WHERE"]
+	if !ok {
+		return
+	}
+	where, ok := c.Expression.(clause.Where)
+	if !ok || len(where.Exprs) == 0 {
+		return
+	}
+	if or, ok := where.Exprs[0].(clause.OrConditions); ok && len(where.Exprs) == 1 {
+		where.Exprs[0] = clause.AndConditions{Exprs: or.Exprs}
+	} else if len(where.Exprs) > 1 {
+		where.Exprs = []clause.Expression{clause.And(where.Exprs...)}
+	}
+	c.Expression = where
+	stmt.Clauses["WHERE"] = c
+}
+
+func assign(db *gorm.DB) {
+	t, ok := tenantOf(db)
+	if !ok {
+		return
+	}
+	switch model(db).(type) {
+	case models.TenantOwned, models.TenantMember:
+		if refuse(db, t) {
+			return
+		}
+	}
+	eachCreated(db, func(v interface{}) {
+		if m, ok := v.(models.TenantOwned); ok {
+			m.SetOrganizationID(t.OrganizationID)
+		}
+	})
+}
+
+func join(db *gorm.DB) {
+	t, ok := tenantOf(db)
+	if !ok || db.Error != nil {
+		return
+	}
+	eachCreated(db, func(v interface{}) {
+		if m, ok := v.(models.TenantMember); ok {
+			if err := m.JoinOrganization(db, t.OrganizationID); err != nil {
+				db.AddError(err)
+			}
+		}
+	})
+}
+
+func eachCreated(db *gorm.DB, fn func(v interface{})) {
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Struct:
+		if rv.CanAddr() {
+			fn(rv.Addr().Interface())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			item := reflect.Indirect(rv.Index(i))
+			if item.CanAddr() {
+				fn(item.Addr().Interface())
+			}
+		}
+	}
+}
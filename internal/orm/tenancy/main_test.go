@@ -0,0 +1,67 @@
+package tenancy_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofrs/uuid"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/tenancy"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+	"gorm.io/gorm"
+)
+
+func newMockedDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := orm.NewDBMock(utils.TestServerconf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tenancy.RegisterCallbacks(db); err != nil {
+		t.Fatal(err)
+	}
+	return db, mock
+}
+
+func TestNoOrganizationIsRefused(t *testing.T) {
+	u := &models.User{}
+	u.ID = uuid.Must(uuid.NewV4())
+	db, mock := newMockedDB(t)
+	scoped := tenancy.Scope(db, tenancy.ForUser(u))
+
+	products := []models.Product{}
+	if err := scoped.Find(&products).Error; !errors.Is(err, tenancy.ErrNoOrganization) {
+		t.Errorf("read err = %v, want %v", err, tenancy.ErrNoOrganization)
+	}
+	p := &models.Product{Name: "Widget"}
+	if err := scoped.Create(p).Error; !errors.Is(err, tenancy.ErrNoOrganization) {
+		t.Errorf("create err = %v, want %v", err, tenancy.ErrNoOrganization)
+	}
+	if p.OrganizationID != nil {
+		t.Errorf("product organization = %v, want none", *p.OrganizationID)
+	}
+	// no statement reached the database
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestActiveOrganizationScopesTheReads(t *testing.T) {
+	orgID := uuid.Must(uuid.NewV4())
+	u := &models.User{ActiveOrganizationID: &orgID}
+	db, mock := newMockedDB(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "products" WHERE "products"."organization_id" = \$1`).
+		WithArgs(orgID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	products := []models.Product{}
+	if err := tenancy.Scope(db, tenancy.ForUser(u)).Find(&products).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
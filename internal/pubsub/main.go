@@ -0,0 +1,86 @@
+// Package pubsub carries the events the GraphQL subscriptions are fed with.
+// Events only hold the id of what changed, subscribers load it as the user
+// they act for, so the payloads can go through any broker
+package pubsub
+
+import (
+	"context"
+	"sync"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+)
+
+// Topics
+const (
+	TopicProductCreated = "product.created"
+	TopicProductUpdated = "product.updated"
+	TopicUserUpdated    = "user.updated"
+)
+
+// Event something changed, ID is the id of the entity that did
+type Event struct {
+	Topic string `json:"topic"`
+	ID    string `json:"id"`
+}
+
+// Broker delivers the events published on a topic to its subscribers, a
+// message broker can be plugged in to share the events between instances
+type Broker interface {
+	Publish(ctx context.Context, e Event) error
+	// Subscribe returns the events of the topic until the context is done,
+	// the channel is closed then
+	Subscribe(ctx context.Context, topic string) (<-chan Event, error)
+}
+
+// InProcess delivers the events to the subscribers of the same process.
+// Subscribers that don't keep up lose the events their buffer can't hold
+type InProcess struct {
+	buffer int
+
+	mu          sync.RWMutex
+	subscribers map[string]map[chan Event]struct{}
+}
+
+// NewInProcess returns a broker whose subscribers buffer up to buffer events
+func NewInProcess(buffer int) *InProcess {
+	return &InProcess{
+		buffer:      buffer,
+		subscribers: map[string]map[chan Event]struct{}{},
+	}
+}
+
+// Publish delivers the event without waiting for slow subscribers
+func (b *InProcess) Publish(ctx context.Context, e Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.subscribers[e.Topic] {
+		select {
+		case ch <- e:
+		default:
+			logger.Warnf("[pubsub] subscriber of %s is full, event for %s dropped", e.Topic, e.ID)
+		}
+	}
+	return nil
+}
+
+// Subscribe returns the events of the topic until the context is done
+func (b *InProcess) Subscribe(ctx context.Context, topic string) (<-chan Event, error) {
+	ch := make(chan Event, b.buffer)
+
+	b.mu.Lock()
+	if b.subscribers[topic] == nil {
+		b.subscribers[topic] = map[chan Event]struct{}{}
+	}
+	b.subscribers[topic][ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subscribers[topic], ch)
+		b.mu.Unlock()
+		close(ch)
+	}()
+
+	return ch, nil
+}
@@ -0,0 +1,169 @@
+// Package rbac loads the declarative RBAC policy file and reconciles the
+// roles, permissions and grants in the database with it
+package rbac
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
+	"gopkg.in/yaml.v2"
+)
+
+// PolicyVersion is the policy file version this package understands
+const PolicyVersion = 1
+
+const wildcard = "*"
+
+// Policy is the versioned definition of the roles, permissions and grants
+type Policy struct {
+	Version  int                 `yaml:"version"`
+	Entities map[string][]string `yaml:"entities"`
+	Roles    []RoleDef           `yaml:"roles"`
+	Rules    []RuleDef           `yaml:"rules"`
+}
+
+// RoleDef defines a role and the permission tags granted to it
+type RoleDef struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Permissions []string `yaml:"permissions"`
+}
+
+// RuleDef defines an attribute based rule, they are not synced into the
+// database but evaluated by the authz package before falling back to RBAC
+type RuleDef struct {
+	Description string   `yaml:"description"`
+	Effect      string   `yaml:"effect"` // allow or deny
+	Actions     []string `yaml:"actions"`
+	Entity      string   `yaml:"entity"`
+	Roles       []string `yaml:"roles"` // Optional, the principal must hold one of them
+	Condition   string   `yaml:"condition"`
+}
+
+// LoadPolicy reads and validates the policy file at path
+func LoadPolicy(path string) (*Policy, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("[RBAC.LoadPolicy] %v", err)
+	}
+	p := &Policy{}
+	if err := yaml.UnmarshalStrict(b, p); err != nil {
+		return nil, fmt.Errorf("[RBAC.LoadPolicy] %s: %v", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("[RBAC.LoadPolicy] %s: %v", path, err)
+	}
+	return p, nil
+}
+
+// Validate checks the policy is consistent: known version, unique roles and
+// grants that match at least one declared permission
+func (p *Policy) Validate() error {
+	if p.Version != PolicyVersion {
+		return fmt.Errorf("unsupported policy version [%d]", p.Version)
+	}
+	seen := map[string]bool{}
+	for _, r := range p.Roles {
+		if r.Name == "" {
+			return fmt.Errorf("role without name")
+		}
+		if seen[r.Name] {
+			return fmt.Errorf("role [%s] is declared more than once", r.Name)
+		}
+		seen[r.Name] = true
+		if _, err := p.Grants(r); err != nil {
+			return err
+		}
+	}
+	for i, r := range p.Rules {
+		if r.Effect != "allow" && r.Effect != "deny" {
+			return fmt.Errorf("rule #%d has invalid effect [%s], use allow or deny", i+1, r.Effect)
+		}
+		actions, ok := p.Entities[r.Entity]
+		if !ok {
+			return fmt.Errorf("rule #%d references unknown entity [%s]", i+1, r.Entity)
+		}
+		for _, a := range r.Actions {
+			if !contains(actions, a) {
+				return fmt.Errorf("rule #%d references unknown action [%s:%s]", i+1, a, r.Entity)
+			}
+		}
+		for _, role := range r.Roles {
+			if !seen[role] {
+				return fmt.Errorf("rule #%d references unknown role [%s]", i+1, role)
+			}
+		}
+	}
+	return nil
+}
+
+// PermissionTags returns the sorted action:entity tags declared by the policy
+func (p *Policy) PermissionTags() []string {
+	tags := []string{}
+	for entity, actions := range p.Entities {
+		for _, a := range actions {
+			tags = append(tags, permissionTag(a, entity))
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Grants expands the wildcards of the role's permissions to the sorted list of
+// permission tags granted to it
+func (p *Policy) Grants(r RoleDef) ([]string, error) {
+	all := p.PermissionTags()
+	granted := map[string]bool{}
+	for _, g := range r.Permissions {
+		matched := false
+		for _, t := range all {
+			if matchTag(g, t) {
+				granted[t] = true
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("role [%s] grants [%s] which matches no permission", r.Name, g)
+		}
+	}
+	tags := make([]string, 0, len(granted))
+	for t := range granted {
+		tags = append(tags, t)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+func permissionTag(action string, entity string) string {
+	return consts.FormatPermissionTag(action+":%s", entity)
+}
+
+func permissionDesc(tag string) string {
+	parts := strings.SplitN(tag, ":", 2)
+	return consts.FormatPermissionDesc(parts[0]+":%s", parts[1])
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// matchTag matches a tag against a grant that may use a wildcard on either side
+func matchTag(grant string, tag string) bool {
+	if grant == wildcard || grant == tag {
+		return true
+	}
+	g := strings.SplitN(grant, ":", 2)
+	t := strings.SplitN(tag, ":", 2)
+	if len(g) != 2 || len(t) != 2 {
+		return false
+	}
+	return (g[0] == wildcard || g[0] == t[0]) && (g[1] == wildcard || g[1] == t[1])
+}
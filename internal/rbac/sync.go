@@ -0,0 +1,229 @@
+package rbac
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"gorm.io/gorm"
+)
+
+// Change is a single difference between the policy and the database
+type Change struct {
+	Op      string
+	Subject string
+	Detail  string
+	apply   func(tx *gorm.DB) error
+}
+
+// String formats the change as a diff line
+func (c Change) String() string {
+	if c.Detail == "" {
+		return fmt.Sprintf("%s %s", c.Op, c.Subject)
+	}
+	return fmt.Sprintf("%s %s (%s)", c.Op, c.Subject, c.Detail)
+}
+
+// Plan is the ordered list of changes needed to reconcile the database
+type Plan struct {
+	Changes []Change
+}
+
+// Empty reports if the database is already in sync with the policy
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// String formats the plan as a diff, one change per line
+func (p *Plan) String() string {
+	if p.Empty() {
+		return "RBAC policy is in sync, nothing to do"
+	}
+	lines := make([]string, len(p.Changes))
+	for i, c := range p.Changes {
+		lines[i] = c.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (p *Plan) add(op string, subject string, detail string, apply func(tx *gorm.DB) error) {
+	p.Changes = append(p.Changes, Change{Op: op, Subject: subject, Detail: detail, apply: apply})
+}
+
+// Sync reconciles the roles, permissions and grants in the database with the
+// policy. With dryRun the plan is computed and returned but nothing is written
+func Sync(db *gorm.DB, policy *Policy, dryRun bool) (*Plan, error) {
+	tx := db.Begin()
+	defer tx.Rollback()
+
+	plan, err := diff(tx, policy)
+	if err != nil {
+		return nil, fmt.Errorf("[RBAC.Sync.Diff] %v", err)
+	}
+	if dryRun || plan.Empty() {
+		return plan, nil
+	}
+	for _, c := range plan.Changes {
+		if err := c.apply(tx); err != nil {
+			return nil, fmt.Errorf("[RBAC.Sync] %s: %v", c, err)
+		}
+	}
+	return plan, tx.Commit().Error
+}
+
+// diff computes the plan. Changes are ordered so that everything a grant
+// references exists before it, and revokes happen before deletions
+func diff(tx *gorm.DB, policy *Policy) (*Plan, error) {
+	plan := &Plan{}
+
+	dbPermissions := []*models.Permission{}
+	if err := tx.Find(&dbPermissions).Error; err != nil {
+		return nil, err
+	}
+	dbRoles := []*models.Role{}
+	if err := tx.Preload("Permissions").Order("id").Find(&dbRoles).Error; err != nil {
+		return nil, err
+	}
+
+	// Permissions, keyed by tag. New ones are looked up by tag when granted
+	permissions := map[string]*models.Permission{}
+	for _, p := range dbPermissions {
+		permissions[p.Tag] = p
+	}
+	declared := map[string]bool{}
+	for _, tag := range policy.PermissionTags() {
+		declared[tag] = true
+		desc := permissionDesc(tag)
+		p, ok := permissions[tag]
+		if !ok {
+			p = &models.Permission{Tag: tag, Description: desc}
+			permissions[tag] = p
+			plan.add("+ permission", tag, "", create(p))
+		} else if p.Description != desc {
+			plan.add("~ permission", tag, "description", updateColumn(p, "description", desc))
+		}
+	}
+
+	// Roles, keyed by name
+	roles := map[string]*models.Role{}
+	for _, r := range dbRoles {
+		roles[r.Name] = r
+	}
+	for _, def := range policy.Roles {
+		r, ok := roles[def.Name]
+		if !ok {
+			r = &models.Role{Name: def.Name, Description: def.Description}
+			plan.add("+ role", def.Name, "", create(r))
+		} else if r.Description != def.Description {
+			plan.add("~ role", def.Name, "description", updateColumn(r, "description", def.Description))
+		}
+
+		// Grants of the role
+		grants, err := policy.Grants(def)
+		if err != nil {
+			return nil, err
+		}
+		current := map[string]bool{}
+		for _, p := range r.Permissions {
+			current[p.Tag] = true
+		}
+		for _, tag := range grants {
+			if !current[tag] {
+				plan.add("+ grant", def.Name+" -> "+tag, "", grant(r, permissions[tag]))
+			}
+		}
+		wanted := map[string]bool{}
+		for _, tag := range grants {
+			wanted[tag] = true
+		}
+		for i := range r.Permissions {
+			p := r.Permissions[i]
+			if !wanted[p.Tag] {
+				plan.add("- grant", def.Name+" -> "+p.Tag, "", revoke(r, &p))
+			}
+		}
+	}
+
+	// Roles and permissions that are no longer declared
+	keep := map[string]bool{}
+	for _, def := range policy.Roles {
+		keep[def.Name] = true
+	}
+	for _, r := range dbRoles {
+		if !keep[r.Name] {
+			plan.add("- role", r.Name, "", deleteRole(r))
+		}
+	}
+	for _, p := range dbPermissions {
+		if !declared[p.Tag] {
+			plan.add("- permission", p.Tag, "", deletePermission(p))
+		}
+	}
+	return plan, nil
+}
+
+func create(value interface{}) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		return tx.Create(value).Error
+	}
+}
+
+func updateColumn(model interface{}, column string, value interface{}) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		return tx.Model(model).UpdateColumn(column, value).Error
+	}
+}
+
+// grant adds the permission to the role, the users holding the role get it
+// when their roles are evaluated
+func grant(r *models.Role, p *models.Permission) func(tx *gorm.DB) error {
+	return create(&models.RolePermission{RoleID: r.ID, PermissionID: p.ID})
+}
+
+// revoke removes the permission from the role, and the copies the users'
+// save hooks made of it for the users holding the role, unless another one of
+// their roles still grants it
+func revoke(r *models.Role, p *models.Permission) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ? AND permission_id = ?", r.ID, p.ID).
+			Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`DELETE FROM user_permissions up
+			WHERE up.permission_id = ?
+			AND up.user_id IN (SELECT user_id FROM user_roles WHERE role_id = ?)
+			AND NOT EXISTS (
+				SELECT 1 FROM user_roles ur
+				JOIN role_permissions rp ON rp.role_id = ur.role_id
+				WHERE ur.user_id = up.user_id AND rp.permission_id = up.permission_id)`,
+			p.ID, r.ID).Error
+	}
+}
+
+func deleteRole(r *models.Role) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, p := range r.Permissions {
+			if err := revoke(r, &p)(tx); err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("role_id = ?", r.ID).Delete(&models.UserRole{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM group_roles WHERE role_id = ?", r.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(r).Error
+	}
+}
+
+func deletePermission(p *models.Permission) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, join := range []string{"role_permissions", "user_permissions", "user_api_key_permissions", "group_permissions"} {
+			if err := tx.Exec("DELETE FROM "+join+" WHERE permission_id = ?", p.ID).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(p).Error
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofrs/uuid"
+	"github.com/txbrown/gqlgen-api-starter/internal/authz"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/relay"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/repositories"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/tenancy"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
+)
+
+// ErrUnknownEntity the entity of a permission check can't be loaded by id
+var ErrUnknownEntity = errors.New("unknown entity")
+
+// resourceLoader loads a resource as seen from the tenant
+type resourceLoader func(t tenancy.Tenant, id uuid.UUID) (interface{}, error)
+
+// AccessService answers what the current user can do, through the same
+// authorization code path the API uses
+type AccessService interface {
+	Can(cu *models.User, check model.PermissionCheckInput) (*model.PermissionCheck, error)
+	CanAll(cu *models.User, checks []*model.PermissionCheckInput) ([]*model.PermissionCheck, error)
+}
+
+type accessService struct {
+	policy  authz.Policy
+	loaders map[string]resourceLoader
+}
+
+func NewAccessService(policy authz.Policy, productsRepo repositories.ProductsRepository, usersRepo repositories.UsersRepository, organizationsRepo repositories.OrganizationsRepository) AccessService {
+	return &accessService{
+		policy: policy,
+		loaders: map[string]resourceLoader{
+			consts.GetTableName(consts.EntityNames.Products): func(t tenancy.Tenant, id uuid.UUID) (interface{}, error) {
+				return productsRepo.ForTenant(t).FindById(id)
+			},
+			consts.GetTableName(consts.EntityNames.Users): func(t tenancy.Tenant, id uuid.UUID) (interface{}, error) {
+				return usersRepo.ForTenant(t).FindById(id)
+			},
+			consts.GetTableName(consts.EntityNames.Organizations): func(t tenancy.Tenant, id uuid.UUID) (interface{}, error) {
+				return organizationsRepo.ForTenant(t).FindById(id)
+			},
+		},
+	}
+}
+
+// Can checks a single action
+func (a accessService) Can(cu *models.User, check model.PermissionCheckInput) (*model.PermissionCheck, error) {
+	results, err := a.CanAll(cu, []*model.PermissionCheckInput{&check})
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// CanAll checks the actions in order, loading each resource once
+func (a accessService) CanAll(cu *models.User, checks []*model.PermissionCheckInput) ([]*model.PermissionCheck, error) {
+	t := tenancy.ForUser(cu)
+	resources := map[string]interface{}{}
+	results := []*model.PermissionCheck{}
+
+	for _, c := range checks {
+		entity := consts.GetTableName(c.Entity)
+		result := &model.PermissionCheck{Action: c.Action, Entity: entity, ID: c.ID}
+		results = append(results, result)
+
+		var resource interface{}
+		if c.ID != nil {
+			key := entity + ":" + *c.ID
+			r, ok := resources[key]
+			if !ok {
+				var err error
+				if r, err = a.load(t, entity, *c.ID); err != nil {
+					return nil, err
+				}
+				resources[key] = r
+			}
+			if r == nil {
+				reason := "not found"
+				result.Reason = &reason
+				continue
+			}
+			resource = r
+		}
+
+		_, d := authz.Check(a.policy, cu, c.Action+":%s", entity, resource)
+		result.Allowed = d.Allowed
+		if d.Reason != "" {
+			reason := d.Reason
+			result.Reason = &reason
+		}
+	}
+	return results, nil
+}
+
+// load returns the resource, nil if it doesn't exist in the tenant
+func (a accessService) load(t tenancy.Tenant, entity string, id string) (interface{}, error) {
+	loader, ok := a.loaders[entity]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEntity, entity)
+	}
+	// the ids of the nodes are global ids
+	key, err := relay.Key(id, "")
+	if err != nil {
+		return nil, err
+	}
+	rid, err := parseID(key)
+	if err != nil {
+		return nil, err
+	}
+	r, err := loader(t, rid)
+	if err != nil {
+		return nil, nil
+	}
+	return r, nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"github.com/txbrown/gqlgen-api-starter/internal/authz"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/query"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/repositories"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/tenancy"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
+)
+
+type AuditService interface {
+	Record(e *models.AuditEntry) error
+	List(cu *models.User, filters []*model.QueryFilter, where *model.FilterGroup, limit *int, offset *int) ([]*models.AuditEntry, bool, error)
+	Count(cu *models.User, filters []*model.QueryFilter, where *model.FilterGroup) (int64, error)
+}
+
+type auditService struct {
+	repo   repositories.AuditRepository
+	policy authz.Policy
+}
+
+func NewAuditService(repo repositories.AuditRepository, policy authz.Policy) AuditService {
+	return &auditService{
+		repo:   repo,
+		policy: policy,
+	}
+}
+
+// Record appends the entry to the audit trail
+func (a auditService) Record(e *models.AuditEntry) error {
+	return a.repo.Create(e)
+}
+
+// List returns the audit trail of the current user's tenant, newest first,
+// and whether there is a next page
+func (a auditService) List(cu *models.User, filters []*model.QueryFilter, where *model.FilterGroup, limit *int, offset *int) ([]*models.AuditEntry, bool, error) {
+	if err := authz.Authorize(a.policy, cu, consts.Permissions.List, consts.EntityNames.AuditEntries, nil); err != nil {
+		return nil, false, err
+	}
+	size, skip, err := query.Window(limit, offset)
+	if err != nil {
+		return nil, false, err
+	}
+	peek := size + 1
+	dbRecords, err := a.repo.ForTenant(tenancy.ForUser(cu)).Search(filters, where, &peek, &skip)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(dbRecords) > size {
+		return dbRecords[:size], true, nil
+	}
+	return dbRecords, false, nil
+}
+
+// Count returns the number of entries matching the filters
+func (a auditService) Count(cu *models.User, filters []*model.QueryFilter, where *model.FilterGroup) (int64, error) {
+	if err := authz.Authorize(a.policy, cu, consts.Permissions.List, consts.EntityNames.AuditEntries, nil); err != nil {
+		return 0, err
+	}
+	return a.repo.ForTenant(tenancy.ForUser(cu)).Count(filters, where)
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gofrs/uuid"
+	"github.com/txbrown/gqlgen-api-starter/internal/authz"
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/repositories"
+	"github.com/txbrown/gqlgen-api-starter/internal/storage"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
+)
+
+var (
+	// ErrFileTooLarge is returned for uploads over the size limit
+	ErrFileTooLarge = errors.New("file is too large")
+	// ErrFileTypeNotAllowed is returned for uploads of a content type that
+	// isn't accepted
+	ErrFileTypeNotAllowed = errors.New("file type is not allowed")
+	// ErrFileSizeMismatch is returned when the upload isn't as long as declared
+	ErrFileSizeMismatch = errors.New("file size does not match its content")
+)
+
+type FilesService interface {
+	Upload(ctx context.Context, cu *models.User, name string, size int64, r io.Reader) (*models.File, error)
+	FindById(cu *models.User, id string) (*models.File, error)
+	Delete(ctx context.Context, cu *models.User, id string) (*models.File, error)
+	URL(storageKey string, name string) (string, error)
+	Open(storageKey string, name string, expires string, signature string) (*os.File, error)
+}
+
+type filesService struct {
+	repo    repositories.FilesRepository
+	storage storage.Storage
+	cfg     utils.StorageConfig
+	policy  authz.Policy
+}
+
+func NewFilesService(repo repositories.FilesRepository, storage storage.Storage, cfg utils.StorageConfig, policy authz.Policy) FilesService {
+	return &filesService{
+		repo:    repo,
+		storage: storage,
+		cfg:     cfg,
+		policy:  policy,
+	}
+}
+
+// Upload stores the file for the current user. The content type is sniffed
+// from the content, what the client declares is not trusted
+func (f filesService) Upload(ctx context.Context, cu *models.User, name string, size int64, r io.Reader) (*models.File, error) {
+	if err := authz.Authorize(f.policy, cu, consts.Permissions.Upload, consts.EntityNames.File, nil); err != nil {
+		return nil, err
+	}
+	if f.cfg.MaxUploadSize > 0 && size > f.cfg.MaxUploadSize {
+		return nil, ErrFileTooLarge
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	head = head[:n]
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil || !f.allowed(contentType) {
+		return nil, ErrFileTypeNotAllowed
+	}
+
+	key := "files/" + uuid.Must(uuid.NewV4()).String()
+	hash := sha256.New()
+	content := &countingReader{r: io.TeeReader(io.LimitReader(io.MultiReader(bytes.NewReader(head), r), size+1), hash)}
+	if err := f.storage.Put(ctx, key, content, size, contentType); err != nil {
+		return nil, fmt.Errorf("[FilesService.Upload] %v", err)
+	}
+	if content.n != size {
+		f.remove(ctx, key)
+		return nil, ErrFileSizeMismatch
+	}
+
+	dbo := &models.File{
+		OwnerID:     cu.ID,
+		Name:        filepath.Base(name),
+		ContentType: contentType,
+		Size:        size,
+		Checksum:    hex.EncodeToString(hash.Sum(nil)),
+		StorageKey:  key,
+	}
+	if err := f.repo.Create(dbo); err != nil {
+		f.remove(ctx, key)
+		return nil, err
+	}
+	return dbo, nil
+}
+
+func (f filesService) FindById(cu *models.User, id string) (*models.File, error) {
+	fileID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	dbo, err := f.repo.FindById(fileID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authz.Authorize(f.policy, cu, consts.Permissions.Read, consts.EntityNames.File, dbo); err != nil {
+		return nil, err
+	}
+	return dbo, nil
+}
+
+// Delete removes the file's record, then its content
+func (f filesService) Delete(ctx context.Context, cu *models.User, id string) (*models.File, error) {
+	fileID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	dbo, err := f.repo.FindById(fileID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authz.Authorize(f.policy, cu, consts.Permissions.Delete, consts.EntityNames.File, dbo); err != nil {
+		return nil, err
+	}
+	if err := f.repo.Delete(dbo); err != nil {
+		return nil, err
+	}
+	f.remove(ctx, dbo.StorageKey)
+	return dbo, nil
+}
+
+// URL returns the signed download URL of the file stored under the key
+func (f filesService) URL(storageKey string, name string) (string, error) {
+	return f.storage.SignedURL(storageKey, name, f.cfg.URLExpiry)
+}
+
+// Open returns the content of a signed download URL of the local storage,
+// the other drivers serve their URLs themselves
+func (f filesService) Open(storageKey string, name string, expires string, signature string) (*os.File, error) {
+	local, ok := f.storage.(*storage.Local)
+	if !ok {
+		return nil, storage.ErrInvalidSignature
+	}
+	return local.Open(storageKey, name, expires, signature)
+}
+
+func (f filesService) allowed(contentType string) bool {
+	for _, t := range f.cfg.AllowedContentTypes {
+		if t == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// remove deletes stored content, failures only leave an orphan behind
+func (f filesService) remove(ctx context.Context, key string) {
+	if err := f.storage.Delete(ctx, key); err != nil {
+		logger.Errorf("[FilesService] delete %s: %v", key, err)
+	}
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
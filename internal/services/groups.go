@@ -0,0 +1,286 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/txbrown/gqlgen-api-starter/internal/authz"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/query"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/repositories"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/tenancy"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
+)
+
+// maxGroupDepth bounds the nesting of groups
+const maxGroupDepth = 16
+
+var (
+	// ErrGroupCycle a group can't be nested in itself or in its subgroups
+	ErrGroupCycle = errors.New("a group can't be nested in itself or in its subgroups")
+	// ErrGroupTooDeep the group is nested deeper than maxGroupDepth
+	ErrGroupTooDeep = errors.New("groups are nested too deep")
+	// ErrUnknownPermission a permission granted to a group does not exist
+	ErrUnknownPermission = errors.New("unknown permission")
+)
+
+type GroupsService interface {
+	FindById(cu *models.User, id string) (*models.Group, error)
+	List(cu *models.User, id *string, limit *int, offset *int) ([]*models.Group, bool, error)
+	Count(cu *models.User, id *string) (int64, error)
+	Create(cu *models.User, input model.GroupInput) (*models.Group, error)
+	Update(cu *models.User, id string, input model.GroupInput) (*models.Group, error)
+	Delete(cu *models.User, id string) error
+	AddMember(cu *models.User, groupID string, userID string) (*models.Group, error)
+	RemoveMember(cu *models.User, groupID string, userID string) (*models.Group, error)
+	SetGrants(cu *models.User, groupID string, roles []string, permissions []string) (*models.Group, error)
+	ApplyGrants(u *models.User) error
+}
+
+type groupsService struct {
+	repo      repositories.GroupsRepository
+	usersRepo repositories.UsersRepository
+	rolesRepo repositories.RolesRepository
+	policy    authz.Policy
+}
+
+func NewGroupsService(repo repositories.GroupsRepository, usersRepo repositories.UsersRepository, rolesRepo repositories.RolesRepository, policy authz.Policy) GroupsService {
+	return &groupsService{
+		repo:      repo,
+		usersRepo: usersRepo,
+		rolesRepo: rolesRepo,
+		policy:    policy,
+	}
+}
+
+// FindById returns the group, as seen from the current user's tenant
+func (g groupsService) FindById(cu *models.User, id string) (*models.Group, error) {
+	return g.find(cu, consts.Permissions.Read, id)
+}
+
+// List returns the page of groups, and whether there is a next one
+func (g groupsService) List(cu *models.User, id *string, limit *int, offset *int) ([]*models.Group, bool, error) {
+	if err := authz.Authorize(g.policy, cu, consts.Permissions.List, consts.EntityNames.Groups, nil); err != nil {
+		return nil, false, err
+	}
+	size, skip, err := query.Window(limit, offset)
+	if err != nil {
+		return nil, false, err
+	}
+	peek := size + 1
+	dbRecords, err := g.repo.ForTenant(tenancy.ForUser(cu)).List(id, &peek, &skip)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(dbRecords) > size {
+		return dbRecords[:size], true, nil
+	}
+	return dbRecords, false, nil
+}
+
+// Count returns the number of groups
+func (g groupsService) Count(cu *models.User, id *string) (int64, error) {
+	if err := authz.Authorize(g.policy, cu, consts.Permissions.List, consts.EntityNames.Groups, nil); err != nil {
+		return 0, err
+	}
+	return g.repo.ForTenant(tenancy.ForUser(cu)).Count(id)
+}
+
+func (g groupsService) Create(cu *models.User, input model.GroupInput) (*models.Group, error) {
+	dbo := &models.Group{Name: input.Name}
+	if input.Description != nil {
+		dbo.Description = *input.Description
+	}
+	if err := authz.Authorize(g.policy, cu, consts.Permissions.Create, consts.EntityNames.Groups, dbo); err != nil {
+		return nil, err
+	}
+	if err := g.setParent(cu, dbo, input.ParentID); err != nil {
+		return nil, err
+	}
+	repo := g.repo.ForTenant(tenancy.ForUser(cu))
+	if err := repo.Create(dbo); err != nil {
+		return nil, err
+	}
+	return repo.FindById(dbo.ID)
+}
+
+func (g groupsService) Update(cu *models.User, id string, input model.GroupInput) (*models.Group, error) {
+	dbo, err := g.find(cu, consts.Permissions.Update, id)
+	if err != nil {
+		return nil, err
+	}
+	dbo.Name = input.Name
+	if input.Description != nil {
+		dbo.Description = *input.Description
+	}
+	if err := g.setParent(cu, dbo, input.ParentID); err != nil {
+		return nil, err
+	}
+	repo := g.repo.ForTenant(tenancy.ForUser(cu))
+	if err := repo.Update(dbo); err != nil {
+		return nil, err
+	}
+	return repo.FindById(dbo.ID)
+}
+
+func (g groupsService) Delete(cu *models.User, id string) error {
+	dbo, err := g.find(cu, consts.Permissions.Delete, id)
+	if err != nil {
+		return err
+	}
+	return g.repo.ForTenant(tenancy.ForUser(cu)).Delete(dbo.ID)
+}
+
+// AddMember adds the user, who must be visible in the current user's tenant
+func (g groupsService) AddMember(cu *models.User, groupID string, userID string) (*models.Group, error) {
+	dbo, u, err := g.member(cu, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+	repo := g.repo.ForTenant(tenancy.ForUser(cu))
+	if err := repo.AddMember(dbo, u); err != nil {
+		return nil, err
+	}
+	return repo.FindById(dbo.ID)
+}
+
+func (g groupsService) RemoveMember(cu *models.User, groupID string, userID string) (*models.Group, error) {
+	dbo, u, err := g.member(cu, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+	repo := g.repo.ForTenant(tenancy.ForUser(cu))
+	if err := repo.RemoveMember(dbo, u); err != nil {
+		return nil, err
+	}
+	return repo.FindById(dbo.ID)
+}
+
+// SetGrants replaces the roles and permissions of the group. Besides managing
+// the group, the current user must be able to assign roles and permissions.
+// The groups of an organization can't grant platform roles
+func (g groupsService) SetGrants(cu *models.User, groupID string, roles []string, permissions []string) (*models.Group, error) {
+	dbo, err := g.find(cu, consts.Permissions.Assign, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authz.Authorize(g.policy, cu, consts.Permissions.Assign, consts.EntityNames.Roles, dbo); err != nil {
+		return nil, err
+	}
+	if err := authz.Authorize(g.policy, cu, consts.Permissions.Assign, consts.EntityNames.Permissions, dbo); err != nil {
+		return nil, err
+	}
+	if dbo.OrganizationID != nil {
+		for _, role := range roles {
+			if role == consts.PlatformAdminRole {
+				return nil, ErrPlatformRole
+			}
+		}
+	}
+	dbRoles, err := g.rolesRepo.FindByNames(roles)
+	if err != nil {
+		return nil, err
+	}
+	if len(dbRoles) != len(roles) {
+		return nil, ErrUnknownRole
+	}
+	dbPermissions, err := g.rolesRepo.FindPermissionsByTags(permissions)
+	if err != nil {
+		return nil, err
+	}
+	if len(dbPermissions) != len(permissions) {
+		return nil, ErrUnknownPermission
+	}
+	repo := g.repo.ForTenant(tenancy.ForUser(cu))
+	if err := repo.ReplaceGrants(dbo, dbRoles, dbPermissions); err != nil {
+		return nil, err
+	}
+	return repo.FindById(dbo.ID)
+}
+
+// ApplyGrants adds the roles and permissions of the user's groups, in the
+// active organization and outside of any, to the user's own. The effective
+// permissions are the union of both
+func (g groupsService) ApplyGrants(u *models.User) error {
+	orgID := uuid.Nil
+	if u.ActiveOrganizationID != nil {
+		orgID = *u.ActiveOrganizationID
+	}
+	roles, permissions, err := g.repo.Grants(u.ID, orgID)
+	if err != nil {
+		return err
+	}
+	for _, r := range roles {
+		if !u.HasRoleName(r.Name) {
+			u.Roles = append(u.Roles, r)
+		}
+	}
+	u.Permissions = append(u.Permissions, permissions...)
+	return nil
+}
+
+// find loads the group from the current user's tenant and authorizes the
+// action on it
+func (g groupsService) find(cu *models.User, action string, id string) (*models.Group, error) {
+	groupID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	dbo, err := g.repo.ForTenant(tenancy.ForUser(cu)).FindById(groupID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authz.Authorize(g.policy, cu, action, consts.EntityNames.Groups, dbo); err != nil {
+		return nil, err
+	}
+	return dbo, nil
+}
+
+// member loads the group to manage the membership of, and the user
+func (g groupsService) member(cu *models.User, groupID string, userID string) (*models.Group, *models.User, error) {
+	dbo, err := g.find(cu, consts.Permissions.Assign, groupID)
+	if err != nil {
+		return nil, nil, err
+	}
+	uid, err := parseID(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	u, err := g.usersRepo.ForTenant(tenancy.ForUser(cu)).FindById(uid)
+	if err != nil {
+		return nil, nil, err
+	}
+	return dbo, u, nil
+}
+
+// setParent nests the group in the parent, walking up the parent's ancestors
+// to refuse cycles
+func (g groupsService) setParent(cu *models.User, dbo *models.Group, parentID *string) error {
+	if parentID == nil {
+		dbo.ParentID = nil
+		return nil
+	}
+	parent, err := g.find(cu, consts.Permissions.Read, *parentID)
+	if err != nil {
+		return err
+	}
+	repo := g.repo.ForTenant(tenancy.ForUser(cu))
+	for ancestor, depth := parent, 0; ; depth++ {
+		if ancestor.ID == dbo.ID {
+			return ErrGroupCycle
+		}
+		if ancestor.ParentID == nil {
+			break
+		}
+		if depth >= maxGroupDepth {
+			return ErrGroupTooDeep
+		}
+		if ancestor, err = repo.FindById(*ancestor.ParentID); err != nil {
+			return err
+		}
+	}
+	dbo.ParentID = &parent.ID
+	dbo.Parent = nil
+	return nil
+}
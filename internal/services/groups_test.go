@@ -0,0 +1,64 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/repositories"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/tenancy"
+	"gorm.io/gorm"
+)
+
+// fakeGroups keeps one group in memory
+type fakeGroups struct {
+	repositories.GroupsRepository
+	group *models.Group
+}
+
+func (f *fakeGroups) ForTenant(t tenancy.Tenant) repositories.GroupsRepository {
+	return f
+}
+
+func (f *fakeGroups) FindById(id uuid.UUID) (*models.Group, error) {
+	if id != f.group.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return f.group, nil
+}
+
+func (f *fakeGroups) ReplaceGrants(i *models.Group, roles []models.Role, permissions []models.Permission) error {
+	i.Roles = roles
+	i.Permissions = permissions
+	return nil
+}
+
+func TestSetGrants(t *testing.T) {
+	orgID := uuid.Must(uuid.NewV4())
+	cu := testUser([]string{"admin"}, "assign:groups", "assign:roles", "assign:permissions")
+	tests := []struct {
+		name           string
+		organizationID *uuid.UUID
+		roles          []string
+		err            error
+	}{
+		{name: "organization role", organizationID: &orgID, roles: []string{"editor"}},
+		{name: "platform role in an organization", organizationID: &orgID, roles: []string{"admin"}, err: ErrPlatformRole},
+		{name: "platform role outside of any organization", roles: []string{"admin"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group := &models.Group{OrganizationID: tt.organizationID, Name: "Staff"}
+			group.ID = uuid.Must(uuid.NewV4())
+			gs := NewGroupsService(&fakeGroups{group: group}, newFakeUsers(cu), fakeRoles{}, testPolicy(t))
+
+			_, err := gs.SetGrants(cu, group.ID.String(), tt.roles, nil)
+			if err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if granted := len(group.Roles) > 0; granted != (tt.err == nil) {
+				t.Errorf("roles granted = %v, want %v", granted, tt.err == nil)
+			}
+		})
+	}
+}
@@ -1,6 +1,35 @@
 package services
 
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofrs/uuid"
+	"github.com/txbrown/gqlgen-api-starter/internal/authz"
+)
+
+// ErrInvalidID is returned for ids that are not valid UUIDs
+var ErrInvalidID = errors.New("invalid id")
+
 type Services struct {
-	UsersService    UsersService
-	ProductsService ProductsService
+	UsersService            UsersService
+	ProductsService         ProductsService
+	OrganizationsService    OrganizationsService
+	AuditService            AuditService
+	AccessService           AccessService
+	GroupsService           GroupsService
+	PersistedQueriesService PersistedQueriesService
+	FilesService            FilesService
+	// Policy is the authorization policy of the services, for the checks
+	// made outside of them
+	Policy authz.Policy
+}
+
+// parseID parses the UUID of an entity, wrapping ErrInvalidID
+func parseID(id string) (uuid.UUID, error) {
+	parsed, err := uuid.FromString(id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: %s", ErrInvalidID, id)
+	}
+	return parsed, nil
 }
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/txbrown/gqlgen-api-starter/internal/authz"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/rbac"
+)
+
+// testPolicy returns the policy of the app's rbac.yml, with its rules
+func testPolicy(t *testing.T) authz.Policy {
+	t.Helper()
+	policy, err := rbac.LoadPolicy("../../rbac.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine, err := authz.NewEngine(policy, authz.RBAC{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+// testUser returns a user with the roles and the permission tags
+func testUser(roles []string, tags ...string) *models.User {
+	u := &models.User{Email: "user@example.com"}
+	u.ID = uuid.Must(uuid.NewV4())
+	for _, name := range roles {
+		u.Roles = append(u.Roles, models.Role{Name: name})
+	}
+	for _, tag := range tags {
+		u.Permissions = append(u.Permissions, models.Permission{Tag: tag})
+	}
+	return u
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func strPtr(s string) *string {
+	return &s
+}
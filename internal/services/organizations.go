@@ -0,0 +1,176 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/txbrown/gqlgen-api-starter/internal/authz"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/repositories"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/tenancy"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
+	"gorm.io/gorm"
+)
+
+// OrganizationOwnerRole is the role given to the creator of an organization
+const OrganizationOwnerRole = "owner"
+
+var (
+	// ErrNotMember the user is not a member of the organization
+	ErrNotMember = errors.New("user is not a member of the organization")
+	// ErrUnknownRole a role given to a member does not exist
+	ErrUnknownRole = errors.New("unknown role")
+	// ErrPlatformRole the platform roles can't be granted within an
+	// organization
+	ErrPlatformRole = errors.New("platform roles can't be granted in an organization")
+)
+
+type OrganizationsService interface {
+	Create(cu *models.User, input model.OrganizationInput) (*models.Organization, error)
+	ForUser(cu *models.User) ([]*models.Organization, error)
+	FindMember(cu *models.User, organizationID string, userID string) (*models.OrganizationMember, error)
+	SaveMember(cu *models.User, organizationID string, userID string, roles []string) (*models.OrganizationMember, error)
+	RemoveMember(cu *models.User, organizationID string, userID string) error
+	Activate(u *models.User, organizationID string) error
+}
+
+type organizationsService struct {
+	repo      repositories.OrganizationsRepository
+	usersRepo repositories.UsersRepository
+	rolesRepo repositories.RolesRepository
+	policy    authz.Policy
+}
+
+func NewOrganizationsService(repo repositories.OrganizationsRepository, usersRepo repositories.UsersRepository, rolesRepo repositories.RolesRepository, policy authz.Policy) OrganizationsService {
+	return &organizationsService{
+		repo:      repo,
+		usersRepo: usersRepo,
+		rolesRepo: rolesRepo,
+		policy:    policy,
+	}
+}
+
+// Create creates the organization with the current user as its owner
+func (o organizationsService) Create(cu *models.User, input model.OrganizationInput) (*models.Organization, error) {
+	dbo := &models.Organization{Name: input.Name, Slug: input.Slug}
+	if err := authz.Authorize(o.policy, cu, consts.Permissions.Create, consts.EntityNames.Organizations, dbo); err != nil {
+		return nil, err
+	}
+	roles, err := o.rolesRepo.FindByNames([]string{OrganizationOwnerRole})
+	if err != nil {
+		return nil, err
+	}
+	owner := &models.OrganizationMember{UserID: cu.ID, Roles: roles}
+	if err := o.repo.Create(dbo, owner); err != nil {
+		return nil, err
+	}
+	return dbo, nil
+}
+
+// ForUser returns the organizations the current user is a member of
+func (o organizationsService) ForUser(cu *models.User) ([]*models.Organization, error) {
+	return o.repo.FindByUser(cu.ID)
+}
+
+// FindMember returns the user's membership in the organization, the current
+// user must be able to manage its members
+func (o organizationsService) FindMember(cu *models.User, organizationID string, userID string) (*models.OrganizationMember, error) {
+	org, uid, err := o.assignable(cu, organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return o.repo.FindMember(org.ID, uid)
+}
+
+// SaveMember adds the user to the organization, or replaces its roles. The
+// current user must be able to manage the organization's members, the roles
+// can't be platform roles
+func (o organizationsService) SaveMember(cu *models.User, organizationID string, userID string, roles []string) (*models.OrganizationMember, error) {
+	org, uid, err := o.assignable(cu, organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		if role == consts.PlatformAdminRole {
+			return nil, ErrPlatformRole
+		}
+	}
+	dbRoles, err := o.rolesRepo.FindByNames(roles)
+	if err != nil {
+		return nil, err
+	}
+	if len(dbRoles) != len(roles) {
+		return nil, ErrUnknownRole
+	}
+	member, err := o.repo.FindMember(org.ID, uid)
+	if err == gorm.ErrRecordNotFound {
+		// new members aren't in the tenant yet
+		if _, err := o.usersRepo.FindById(uid); err != nil {
+			return nil, err
+		}
+		member = &models.OrganizationMember{OrganizationID: org.ID, UserID: uid}
+	} else if err != nil {
+		return nil, err
+	}
+	member.Roles = dbRoles
+	if err := o.repo.SaveMember(member); err != nil {
+		return nil, err
+	}
+	return o.repo.FindMember(org.ID, uid)
+}
+
+// RemoveMember removes the user from the organization
+func (o organizationsService) RemoveMember(cu *models.User, organizationID string, userID string) error {
+	org, uid, err := o.assignable(cu, organizationID, userID)
+	if err != nil {
+		return err
+	}
+	return o.repo.DeleteMember(org.ID, uid)
+}
+
+// assignable loads the organization, as seen from the current user's tenant,
+// and checks the user can manage its members
+func (o organizationsService) assignable(cu *models.User, organizationID string, userID string) (*models.Organization, uuid.UUID, error) {
+	orgID, err := parseID(organizationID)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	uid, err := parseID(userID)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	org, err := o.repo.ForTenant(tenancy.ForUser(cu)).FindById(orgID)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	if err := authz.Authorize(o.policy, cu, consts.Permissions.Assign, consts.EntityNames.Organizations, org); err != nil {
+		return nil, uuid.Nil, err
+	}
+	return org, uid, nil
+}
+
+// Activate makes the organization the one the user acts in, adding the
+// permissions of the user's roles in it. Platform admins can activate any
+// organization
+func (o organizationsService) Activate(u *models.User, organizationID string) error {
+	orgID, err := parseID(organizationID)
+	if err != nil {
+		return err
+	}
+	member, err := o.repo.FindMember(orgID, u.ID)
+	switch {
+	case err == nil:
+		u.Permissions = append(u.Permissions, member.Permissions()...)
+	case err == gorm.ErrRecordNotFound && u.IsPlatformAdmin():
+		if _, err := o.repo.FindById(orgID); err != nil {
+			return err
+		}
+	case err == gorm.ErrRecordNotFound:
+		return ErrNotMember
+	default:
+		return err
+	}
+	u.ActiveOrganizationID = &orgID
+	return nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/repositories"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/tenancy"
+	"gorm.io/gorm"
+)
+
+// fakeOrganizations keeps one organization and its members in memory
+type fakeOrganizations struct {
+	repositories.OrganizationsRepository
+	org     *models.Organization
+	members map[uuid.UUID]*models.OrganizationMember
+}
+
+func (f *fakeOrganizations) ForTenant(t tenancy.Tenant) repositories.OrganizationsRepository {
+	return f
+}
+
+func (f *fakeOrganizations) FindById(id uuid.UUID) (*models.Organization, error) {
+	if id != f.org.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return f.org, nil
+}
+
+func (f *fakeOrganizations) FindMember(organizationID uuid.UUID, userID uuid.UUID) (*models.OrganizationMember, error) {
+	m, ok := f.members[userID]
+	if !ok || organizationID != f.org.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return m, nil
+}
+
+func (f *fakeOrganizations) SaveMember(i *models.OrganizationMember) error {
+	f.members[i.UserID] = i
+	return nil
+}
+
+// fakeRoles knows the roles of the names and the permissions of the tags
+type fakeRoles struct {
+	repositories.RolesRepository
+}
+
+func (fakeRoles) FindByNames(names []string) ([]models.Role, error) {
+	roles := []models.Role{}
+	for _, name := range names {
+		roles = append(roles, models.Role{Name: name})
+	}
+	return roles, nil
+}
+
+func (fakeRoles) FindPermissionsByTags(tags []string) ([]models.Permission, error) {
+	permissions := []models.Permission{}
+	for _, tag := range tags {
+		permissions = append(permissions, models.Permission{Tag: tag})
+	}
+	return permissions, nil
+}
+
+func TestSaveMember(t *testing.T) {
+	org := &models.Organization{Name: "Acme"}
+	org.ID = uuid.Must(uuid.NewV4())
+	owner := testUser([]string{"owner"}, "assign:organizations")
+	owner.ActiveOrganizationID = &org.ID
+	// the candidate isn't a member of any organization yet
+	candidate := testUser(nil)
+
+	tests := []struct {
+		name   string
+		userID uuid.UUID
+		roles  []string
+		err    error
+	}{
+		{name: "new member", userID: candidate.ID, roles: []string{"editor"}},
+		{name: "unknown user", userID: uuid.Must(uuid.NewV4()), roles: []string{"editor"}, err: gorm.ErrRecordNotFound},
+		{name: "platform role", userID: candidate.ID, roles: []string{"admin"}, err: ErrPlatformRole},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgs := &fakeOrganizations{org: org, members: map[uuid.UUID]*models.OrganizationMember{}}
+			os := NewOrganizationsService(orgs, newFakeUsers(owner, candidate), fakeRoles{}, testPolicy(t))
+
+			_, err := os.SaveMember(owner, org.ID.String(), tt.userID.String(), tt.roles)
+			if err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if _, saved := orgs.members[tt.userID]; saved != (tt.err == nil) {
+				t.Errorf("member saved = %v, want %v", saved, tt.err == nil)
+			}
+		})
+	}
+}
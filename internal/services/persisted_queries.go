@@ -0,0 +1,64 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/repositories"
+	"gorm.io/gorm"
+)
+
+type PersistedQueriesService interface {
+	Find(hash string) (string, bool, error)
+	FindTrusted(hash string) (string, bool, error)
+	Register(hash string, query string) error
+	Import(queries []*models.PersistedQuery) error
+}
+
+type persistedQueriesService struct {
+	repo repositories.PersistedQueriesRepository
+}
+
+func NewPersistedQueriesService(repo repositories.PersistedQueriesRepository) PersistedQueriesService {
+	return &persistedQueriesService{
+		repo: repo,
+	}
+}
+
+// Find returns the query stored with the hash, if any
+func (p persistedQueriesService) Find(hash string) (string, bool, error) {
+	q, err := p.repo.FindByHash(hash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return q.Query, true, nil
+}
+
+// FindTrusted returns the query stored with the hash, if it was imported from
+// a manifest
+func (p persistedQueriesService) FindTrusted(hash string) (string, bool, error) {
+	q, err := p.repo.FindByHash(hash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return q.Query, q.Trusted, nil
+}
+
+// Register stores a query registered by a client, untrusted
+func (p persistedQueriesService) Register(hash string, query string) error {
+	return p.repo.Create(&models.PersistedQuery{Hash: hash, Query: query})
+}
+
+// Import stores the queries of a client build's manifest as trusted
+func (p persistedQueriesService) Import(queries []*models.PersistedQuery) error {
+	for _, q := range queries {
+		q.Trusted = true
+	}
+	return p.repo.Upsert(queries)
+}
@@ -1,29 +1,205 @@
 package services
 
 import (
+	"context"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/authz"
 	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
 	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/pagination"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/query"
 	"github.com/txbrown/gqlgen-api-starter/internal/orm/repositories"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/tenancy"
+	"github.com/txbrown/gqlgen-api-starter/internal/pubsub"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
 )
 
 type ProductsService interface {
-	Create(i *models.Product) error
-	Products(id *string, filters []*model.QueryFilter, limit *int, offset *int, orderBy *string, sortDirection *string) ([]*models.Product, error)
+	FindById(cu *models.User, id string) (*models.Product, error)
+	FindByIds(cu *models.User, ids []string) ([]*models.Product, error)
+	Create(cu *models.User, i *models.Product) error
+	Update(cu *models.User, id string, input model.ProductInput) (*models.Product, error)
+	Page(cu *models.User, filters []*model.QueryFilter, where *model.FilterGroup, args pagination.Args) ([]*models.Product, *pagination.Page, error)
+	Products(cu *models.User, id *string, filters []*model.QueryFilter, where *model.FilterGroup, limit *int, offset *int, sort []query.Sort) ([]*models.Product, error)
+	List(cu *models.User, id *string, filters []*model.QueryFilter, where *model.FilterGroup, limit *int, offset *int, sort []query.Sort) ([]*models.Product, bool, error)
+	Count(cu *models.User, id *string, filters []*model.QueryFilter, where *model.FilterGroup) (int64, error)
+	Subscribe(ctx context.Context, cu *models.User, topic string, id *string) (<-chan *models.Product, error)
 }
 
 type productsService struct {
-	repo repositories.ProductsRepository
+	repo   repositories.ProductsRepository
+	policy authz.Policy
+	events pubsub.Broker
+}
+
+func NewProductsService(productsRepository repositories.ProductsRepository, policy authz.Policy, events pubsub.Broker) ProductsService {
+	return &productsService{
+		repo:   productsRepository,
+		policy: policy,
+		events: events,
+	}
+}
+
+// FindById returns the product, as seen from the current user's tenant
+func (p productsService) FindById(cu *models.User, id string) (*models.Product, error) {
+	productID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	dbo, err := p.repo.ForTenant(tenancy.ForUser(cu)).FindById(productID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authz.Authorize(p.policy, cu, consts.Permissions.Read, consts.EntityNames.Products, dbo); err != nil {
+		return nil, err
+	}
+	return dbo, nil
+}
+
+// FindByIds returns the products, as seen from the current user's tenant, the
+// ones the current user can't read are left out
+func (p productsService) FindByIds(cu *models.User, ids []string) ([]*models.Product, error) {
+	productIDs, err := parseIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	dbRecords, err := p.repo.ForTenant(tenancy.ForUser(cu)).FindByIds(productIDs)
+	if err != nil {
+		return nil, err
+	}
+	readable := []*models.Product{}
+	for _, dbo := range dbRecords {
+		if authz.Authorize(p.policy, cu, consts.Permissions.Read, consts.EntityNames.Products, dbo) == nil {
+			readable = append(readable, dbo)
+		}
+	}
+	return readable, nil
 }
 
-func NewProductsService(productsRepository repositories.ProductsRepository) ProductsService {
-	return &productsService{}
+func (p productsService) Create(cu *models.User, i *models.Product) error {
+	if err := authz.Authorize(p.policy, cu, consts.Permissions.Create, consts.EntityNames.Products, i); err != nil {
+		return err
+	}
+
+	if err := p.repo.ForTenant(tenancy.ForUser(cu)).Create(i); err != nil {
+		return err
+	}
+	p.publish(pubsub.TopicProductCreated, i)
+	return nil
 }
 
-func (p productsService) Create(i *models.Product) error {
+func (p productsService) Update(cu *models.User, id string, input model.ProductInput) (*models.Product, error) {
+	productID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := p.repo.ForTenant(tenancy.ForUser(cu))
+
+	dbo, err := repo.FindById(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Authorized against the stored product, rules can constrain what is
+	// being updated, e.g. by its current price
+	if err := authz.Authorize(p.policy, cu, consts.Permissions.Update, consts.EntityNames.Products, dbo); err != nil {
+		return nil, err
+	}
+
+	dbo.Name = input.Name
+	dbo.Description = input.Description
+	dbo.Price = input.Price
+
+	if err := repo.Update(dbo); err != nil {
+		return nil, err
+	}
+	p.publish(pubsub.TopicProductUpdated, dbo)
+
+	return dbo, nil
+}
+
+// Products returns the window of products of the current user's tenant
+func (p productsService) Products(cu *models.User, id *string, filters []*model.QueryFilter, where *model.FilterGroup, limit *int, offset *int, sort []query.Sort) ([]*models.Product, error) {
+	if err := authz.Authorize(p.policy, cu, consts.Permissions.List, consts.EntityNames.Products, nil); err != nil {
+		return nil, err
+	}
+	size, skip, err := query.Window(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return p.repo.ForTenant(tenancy.ForUser(cu)).Products(id, filters, where, &size, &skip, sort)
+}
+
+// List returns the page of products, and whether there is a next one
+func (p productsService) List(cu *models.User, id *string, filters []*model.QueryFilter, where *model.FilterGroup, limit *int, offset *int, sort []query.Sort) ([]*models.Product, bool, error) {
+	if err := authz.Authorize(p.policy, cu, consts.Permissions.List, consts.EntityNames.Products, nil); err != nil {
+		return nil, false, err
+	}
+	size, skip, err := query.Window(limit, offset)
+	if err != nil {
+		return nil, false, err
+	}
+	peek := size + 1
+	dbRecords, err := p.repo.ForTenant(tenancy.ForUser(cu)).Products(id, filters, where, &peek, &skip, sort)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(dbRecords) > size {
+		return dbRecords[:size], true, nil
+	}
+	return dbRecords, false, nil
+}
+
+// Count returns the number of products matching the filters
+func (p productsService) Count(cu *models.User, id *string, filters []*model.QueryFilter, where *model.FilterGroup) (int64, error) {
+	if err := authz.Authorize(p.policy, cu, consts.Permissions.List, consts.EntityNames.Products, nil); err != nil {
+		return 0, err
+	}
+	return p.repo.ForTenant(tenancy.ForUser(cu)).Count(id, filters, where)
+}
+
+// Page returns a keyset paginated page of the current user's tenant products
+func (p productsService) Page(cu *models.User, filters []*model.QueryFilter, where *model.FilterGroup, args pagination.Args) ([]*models.Product, *pagination.Page, error) {
+	if err := authz.Authorize(p.policy, cu, consts.Permissions.List, consts.EntityNames.Products, nil); err != nil {
+		return nil, nil, err
+	}
+	return p.repo.ForTenant(tenancy.ForUser(cu)).Page(filters, where, args)
+}
 
-	return p.repo.Create(i)
+// Subscribe returns the products of the topic's events, or only the ones of
+// the product with the id, as seen by the current user when each event comes
+func (p productsService) Subscribe(ctx context.Context, cu *models.User, topic string, id *string) (<-chan *models.Product, error) {
+	events, err := p.events.Subscribe(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	products := make(chan *models.Product)
+	go func() {
+		defer close(products)
+		for e := range events {
+			if id != nil && e.ID != *id {
+				continue
+			}
+			dbo, err := p.FindById(cu, e.ID)
+			if err != nil {
+				continue
+			}
+			select {
+			case products <- dbo:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return products, nil
 }
 
-func (p productsService) Products(id *string, filters []*model.QueryFilter, limit *int, offset *int, orderBy *string, sortDirection *string) ([]*models.Product, error) {
-	return p.repo.Products(id, filters, limit, offset, orderBy, sortDirection)
+// publish the mutation of the product, subscribers missing it doesn't fail
+// the mutation
+func (p productsService) publish(topic string, i *models.Product) {
+	if err := p.events.Publish(context.Background(), pubsub.Event{Topic: topic, ID: i.ID.String()}); err != nil {
+		logger.Errorf("[ProductsService] publish %s: %v", topic, err)
+	}
 }
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/txbrown/gqlgen-api-starter/internal/authz"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/query"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/repositories"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/tenancy"
+	"github.com/txbrown/gqlgen-api-starter/internal/pubsub"
+)
+
+// fakeProducts records the limits it is queried with
+type fakeProducts struct {
+	repositories.ProductsRepository
+	limits []int
+}
+
+func (f *fakeProducts) ForTenant(t tenancy.Tenant) repositories.ProductsRepository {
+	return f
+}
+
+func (f *fakeProducts) Count(id *string, filters []*model.QueryFilter, where *model.FilterGroup) (int64, error) {
+	f.limits = append(f.limits, 0)
+	return 0, nil
+}
+
+func (f *fakeProducts) Products(id *string, filters []*model.QueryFilter, where *model.FilterGroup, limit *int, offset *int, sort []query.Sort) ([]*models.Product, error) {
+	f.limits = append(f.limits, *limit)
+	return []*models.Product{}, nil
+}
+
+func TestProductsLimits(t *testing.T) {
+	cu := testUser(nil, "list:products")
+	tests := []struct {
+		name  string
+		limit *int
+		size  int
+		err   error
+	}{
+		{name: "zero", limit: intPtr(0), err: query.ErrInvalidLimit},
+		{name: "negative", limit: intPtr(-1), err: query.ErrInvalidLimit},
+		{name: "null", limit: nil, size: query.DefaultLimit},
+		{name: "above the maximum", limit: intPtr(1000), size: query.MaxLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeProducts{}
+			ps := NewProductsService(repo, testPolicy(t), pubsub.NewInProcess(1))
+
+			_, err := ps.Products(cu, nil, nil, nil, tt.limit, nil, nil)
+			if err != tt.err {
+				t.Fatalf("Products err = %v, want %v", err, tt.err)
+			}
+			_, _, err = ps.List(cu, nil, nil, nil, tt.limit, nil, nil)
+			if err != tt.err {
+				t.Fatalf("List err = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				if len(repo.limits) != 0 {
+					t.Error("the repository was queried")
+				}
+				return
+			}
+			// the list peeks at one more to know whether there's a next page
+			if len(repo.limits) != 2 || repo.limits[0] != tt.size || repo.limits[1] != tt.size+1 {
+				t.Errorf("limits = %v, want [%d %d]", repo.limits, tt.size, tt.size+1)
+			}
+		})
+	}
+}
+
+func TestProductsListingNeedsTheListPermission(t *testing.T) {
+	tests := []struct {
+		name string
+		cu   *models.User
+	}{
+		{name: "without the permission", cu: testUser(nil, "read:products")},
+		{name: "anonymous", cu: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeProducts{}
+			ps := NewProductsService(repo, testPolicy(t), pubsub.NewInProcess(1))
+			var denied *authz.DeniedError
+
+			if _, err := ps.Products(tt.cu, nil, nil, nil, nil, nil, nil); !errors.As(err, &denied) {
+				t.Errorf("Products err = %v, want denied", err)
+			}
+			if _, _, err := ps.List(tt.cu, nil, nil, nil, nil, nil, nil); !errors.As(err, &denied) {
+				t.Errorf("List err = %v, want denied", err)
+			}
+			if _, err := ps.Count(tt.cu, nil, nil, nil); !errors.As(err, &denied) {
+				t.Errorf("Count err = %v, want denied", err)
+			}
+			if len(repo.limits) != 0 {
+				t.Error("the repository was queried")
+			}
+		})
+	}
+}
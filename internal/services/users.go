@@ -1,17 +1,23 @@
 package services
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gofrs/uuid"
 	"github.com/markbates/goth"
+	"github.com/txbrown/gqlgen-api-starter/internal/authz"
 	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
 	"github.com/txbrown/gqlgen-api-starter/internal/gql/transformations"
 	"github.com/txbrown/gqlgen-api-starter/internal/logger"
 	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/pagination"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/query"
 	"github.com/txbrown/gqlgen-api-starter/internal/orm/repositories"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/tenancy"
+	"github.com/txbrown/gqlgen-api-starter/internal/pubsub"
 	"github.com/txbrown/gqlgen-api-starter/pkg/auth"
 	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
 	"github.com/txbrown/gqlgen-api-starter/pkg/utils/consts"
@@ -19,8 +25,20 @@ import (
 	"gorm.io/gorm"
 )
 
+var (
+	// ErrInvalidCurrentPassword the current password given to change it is
+	// wrong
+	ErrInvalidCurrentPassword = errors.New("current password is incorrect")
+	// ErrEmptyPassword the new password is empty
+	ErrEmptyPassword = errors.New("password can't be empty")
+	// ErrCredentialsNotOwned the credentials of another user can only be
+	// changed by the platform admins
+	ErrCredentialsNotOwned = errors.New("only platform admins can change the credentials of other users")
+)
+
 type UsersService interface {
 	FindUserByAPIKey(apiKey string) (*models.User, error)
+	FindAPIKey(apiKey string) (*models.UserAPIKey, error)
 	FindUserByJWT(email string, provider string, userID string) (*models.User, error)
 	FindUserByExternalIdentifier(externalUserID string, provider string) (*models.User, error)
 	UpsertUserProfile(input *goth.User) (*models.User, error)
@@ -28,24 +46,35 @@ type UsersService interface {
 	UpsertAppleUserProfile(input *model.BasicUserInput) (*models.User, error)
 	FindUserByEmail(email string, provider string) (*models.User, error)
 
+	FindById(cu *models.User, id string) (*models.User, error)
+	FindByIds(cu *models.User, ids []string) ([]*models.User, error)
+	Profiles(userIDs []string, limit int, offset int) ([]*models.UserProfile, error)
+	FindProfileById(cu *models.User, id int) (*models.UserProfile, error)
 	CreateUpdate(input model.UserInput, update bool, cu *models.User, ids ...string) (*model.User, error)
-	Delete(id string) (bool, error)
-	List(id *string, filters []*model.QueryFilter, limit *int, offset *int, orderBy *string, sortDirection *string) (*model.Users, error)
+	Delete(cu *models.User, id string) (bool, error)
+	Page(cu *models.User, filters []*model.QueryFilter, where *model.FilterGroup, args pagination.Args) ([]*models.User, *pagination.Page, error)
+	Count(cu *models.User, id *string, filters []*model.QueryFilter, where *model.FilterGroup) (int64, error)
+	List(cu *models.User, id *string, filters []*model.QueryFilter, where *model.FilterGroup, limit *int, offset *int, sort []query.Sort) (*model.Users, error)
 	IssueToken(u *models.User, cfg *utils.ServerConfig) (string, error)
-	UpdateProfile(input model.UserInput, userID uuid.UUID, cu *models.User, ids ...string) (*model.User, error)
+	UpdateProfile(cu *models.User, id string, input model.UserProfileInput) (*model.User, error)
+	Subscribe(ctx context.Context, cu *models.User, topic string, id *string) (<-chan *models.User, error)
 }
 
 type usersService struct {
 	userRepo        repositories.UsersRepository
 	userProfileRepo repositories.UserProfilesRepository
 	rolesRepo       repositories.RolesRepository
+	policy          authz.Policy
+	events          pubsub.Broker
 }
 
-func NewUsersService(userRepo repositories.UsersRepository, userProfileRepo repositories.UserProfilesRepository, rolesRepo repositories.RolesRepository) UsersService {
+func NewUsersService(userRepo repositories.UsersRepository, userProfileRepo repositories.UserProfilesRepository, rolesRepo repositories.RolesRepository, policy authz.Policy, events pubsub.Broker) UsersService {
 	return &usersService{
 		userRepo:        userRepo,
 		userProfileRepo: userProfileRepo,
 		rolesRepo:       rolesRepo,
+		policy:          policy,
+		events:          events,
 	}
 }
 
@@ -54,6 +83,11 @@ func (o usersService) FindUserByAPIKey(apiKey string) (*models.User, error) {
 	return o.userRepo.FindUserByAPIKey(apiKey)
 }
 
+// FindAPIKey finds the API key with the user it belongs to
+func (o usersService) FindAPIKey(apiKey string) (*models.UserAPIKey, error) {
+	return o.userRepo.FindAPIKey(apiKey)
+}
+
 // FindUserByJWT finds the user that is related to the APIKey token
 func (o usersService) FindUserByJWT(email string, provider string, userID string) (*models.User, error) {
 	return o.userRepo.FindUserByJWT(email, provider, userID)
@@ -195,107 +229,237 @@ func (o usersService) FindUserByEmail(email string, provider string) (*models.Us
 	return o.userRepo.FindByEmail(email)
 }
 
+// FindById returns the user, as seen from the current user's tenant
+func (us usersService) FindById(cu *models.User, id string) (*models.User, error) {
+	userID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	dbo, err := us.userRepo.ForTenant(tenancy.ForUser(cu)).FindById(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authz.Authorize(us.policy, cu, consts.Permissions.Read, consts.EntityNames.Users, dbo); err != nil {
+		return nil, err
+	}
+	return dbo, nil
+}
+
+// FindByIds returns the users, as seen from the current user's tenant, the
+// ones the current user can't read are left out
+func (us usersService) FindByIds(cu *models.User, ids []string) ([]*models.User, error) {
+	userIDs, err := parseIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	dbRecords, err := us.userRepo.ForTenant(tenancy.ForUser(cu)).FindByIds(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	readable := []*models.User{}
+	for _, dbo := range dbRecords {
+		if authz.Authorize(us.policy, cu, consts.Permissions.Read, consts.EntityNames.Users, dbo) == nil {
+			readable = append(readable, dbo)
+		}
+	}
+	return readable, nil
+}
+
+// Profiles returns a window of the profiles of each of the users, whose
+// access was checked when they were resolved
+func (us usersService) Profiles(userIDs []string, limit int, offset int) ([]*models.UserProfile, error) {
+	ids, err := parseIDs(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	if limit, offset, err = query.Window(&limit, &offset); err != nil {
+		return nil, err
+	}
+	return us.userProfileRepo.FindByUserIds(ids, limit, offset)
+}
+
+// FindProfileById returns the profile, the current user's own profiles are
+// always allowed, the other users' profiles need their user to be visible
+// from the current user's tenant and the read permission on profiles
+func (us usersService) FindProfileById(cu *models.User, id int) (*models.UserProfile, error) {
+	dbo, err := us.userProfileRepo.FindById(id)
+	if err != nil {
+		return nil, err
+	}
+	// the user's own profiles are not scoped to the tenant
+	if dbo.UserID != cu.ID {
+		if _, err := us.userRepo.ForTenant(tenancy.ForUser(cu)).FindById(dbo.UserID); err != nil {
+			return nil, err
+		}
+	}
+	if err := authz.Authorize(us.policy, cu, consts.Permissions.Read, consts.EntityNames.UserProfiles, dbo); err != nil {
+		return nil, err
+	}
+	return dbo, nil
+}
+
 func (us usersService) CreateUpdate(input model.UserInput, update bool, cu *models.User, ids ...string) (*model.User, error) {
 	dbo, err := transformations.GQLInputUserToDBUser(&input, update, cu, ids...)
 	if err != nil {
 		return nil, err
 	}
 
+	repo := us.userRepo.ForTenant(tenancy.ForUser(cu))
 	if !update {
-		_, err = us.userRepo.Create(dbo) // Create the user
+		err = authz.Authorize(us.policy, cu, consts.Permissions.Create, consts.EntityNames.Users, dbo)
+	} else {
+		var current *models.User
+		if current, err = repo.FindById(dbo.ID); err == nil {
+			err = authz.Authorize(us.policy, cu, consts.Permissions.Update, consts.EntityNames.Users, current)
+		}
+		if err == nil && (input.Email != nil || input.Password != nil) {
+			err = credentialsOwned(cu, current)
+		}
+		if err == nil {
+			// only the fields of the input change, the others are kept
+			dbo, err = mergeUserInput(current, input, cu)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !update {
+		_, err = repo.Create(dbo) // Create the user
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		err = us.userRepo.Update(dbo) // Or update it
+		err = repo.Update(dbo) // Or update it
 		if err != nil {
 			return nil, err
 		}
+		us.publish(pubsub.TopicUserUpdated, dbo)
 	}
 
 	return transformations.DBUserToGQLUser(dbo), nil
 }
 
-func (us usersService) UpdateProfile(input model.UserInput, userID uuid.UUID, cu *models.User, ids ...string) (*model.User, error) {
-
-	dbo, err := us.userRepo.FindById(userID)
-
+// UpdateProfile updates the account of the user with the id. Users can update
+// their own, changing their password takes the current one, the other users
+// need the update permission on them, and only the platform admins can change
+// their credentials
+func (us usersService) UpdateProfile(cu *models.User, id string, input model.UserProfileInput) (*model.User, error) {
+	dbo, err := us.account(cu, id, consts.Permissions.Update)
 	if err != nil {
 		return nil, err
 	}
 
-	if input.Email != nil {
-		dbo.Email = *input.Email
+	if input.Email != nil || input.Password != nil {
+		if err := credentialsOwned(cu, dbo); err != nil {
+			return nil, err
+		}
 	}
-
 	if input.Password != nil {
-		pwd, err := generateHashFromPassword(*input.Password)
-
-		if err != nil {
+		if dbo.ID == cu.ID && dbo.Password != "" {
+			if input.CurrentPassword == nil ||
+				bcrypt.CompareHashAndPassword([]byte(dbo.Password), []byte(*input.CurrentPassword)) != nil {
+				return nil, ErrInvalidCurrentPassword
+			}
+		}
+		if *input.Password == "" {
+			return nil, ErrEmptyPassword
+		}
+		if dbo.Password, err = generateHashFromPassword(*input.Password); err != nil {
 			return nil, err
 		}
-
-		dbo.Password = pwd
 	}
-
+	if input.Email != nil {
+		dbo.Email = *input.Email
+	}
+	if input.AvatarURL != nil {
+		dbo.AvatarURL = input.AvatarURL
+	}
+	if input.Name != nil {
+		dbo.Name = input.Name
+	}
 	if input.FirstName != nil {
 		dbo.FirstName = input.FirstName
 	}
-
 	if input.LastName != nil {
 		dbo.LastName = input.LastName
 	}
-
-	if input.AvatarURL != nil {
-		dbo.AvatarURL = input.AvatarURL
+	if input.NickName != nil {
+		dbo.NickName = input.NickName
 	}
-
-	if input.Name != nil {
-		dbo.Name = input.Name
+	if input.Description != nil {
+		dbo.Description = input.Description
+	}
+	if input.Location != nil {
+		dbo.Location = input.Location
 	}
+	dbo.UpdatedByID = &cu.ID
 
-	err = us.userRepo.Update(dbo)
-	if err != nil {
+	if err := us.userRepo.Update(dbo); err != nil {
 		return nil, err
 	}
+	us.publish(pubsub.TopicUserUpdated, dbo)
 
 	return transformations.DBUserToGQLUser(dbo), nil
 }
 
-func (us usersService) Delete(id string) (bool, error) {
-	entity := consts.GetTableName(consts.EntityNames.Users)
-
-	// first check if user with profile exists
-	up, err := us.userProfileRepo.FirstWhere(fmt.Sprintf("user_id = %s", id))
-
+// Delete soft deletes the user with the id, removing their profiles and API
+// keys. Users can delete their own account, the other users need the delete
+// permission on them
+func (us usersService) Delete(cu *models.User, id string) (bool, error) {
+	dbo, err := us.account(cu, id, consts.Permissions.Delete)
 	if err != nil {
 		return false, err
 	}
-
-	// delete user profile
-	if err := us.userProfileRepo.Delete(up.ID); err != nil {
-		logger.Errorfn(entity, err)
+	if err := us.userRepo.Delete(dbo.ID); err != nil {
 		return false, err
 	}
+	return true, nil
+}
 
-	// soft delete user for audit reasons
-	if err := us.userRepo.Delete(uuid.FromStringOrNil(id)); err != nil {
-		logger.Errorfn(entity, err)
-		return false, err
+// account returns the user with the id for the action, the current user's own
+// account is not scoped to the tenant but is still checked by the policy
+func (us usersService) account(cu *models.User, id string, action string) (*models.User, error) {
+	userID, err := parseID(id)
+	if err != nil {
+		return nil, err
 	}
-
-	return true, nil
+	repo := us.userRepo
+	if userID != cu.ID {
+		repo = repo.ForTenant(tenancy.ForUser(cu))
+	}
+	dbo, err := repo.FindById(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authz.Authorize(us.policy, cu, action, consts.EntityNames.Users, dbo); err != nil {
+		return nil, err
+	}
+	return dbo, nil
 }
 
-func (us usersService) List(id *string, filters []*model.QueryFilter, limit *int, offset *int, orderBy *string, sortDirection *string) (*model.Users, error) {
+func (us usersService) List(cu *models.User, id *string, filters []*model.QueryFilter, where *model.FilterGroup, limit *int, offset *int, sort []query.Sort) (*model.Users, error) {
+	if err := authz.Authorize(us.policy, cu, consts.Permissions.List, consts.EntityNames.Users, nil); err != nil {
+		return nil, err
+	}
+
 	record := &model.Users{}
 	dbRecords := []*models.User{}
 
-	dbRecords, err := us.userRepo.Search(id, filters, limit, offset, orderBy, sortDirection)
+	size, skip, err := query.Window(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	peek := size + 1
+	dbRecords, err = us.userRepo.ForTenant(tenancy.ForUser(cu)).Search(id, filters, where, &peek, &skip, sort)
 
 	if err != nil {
 		return nil, err
 	}
+	if len(dbRecords) > size {
+		dbRecords = dbRecords[:size]
+		record.HasNextPage = true
+	}
 
 	for _, dbRec := range dbRecords {
 		record.List = append(record.List, transformations.DBUserToGQLUser(dbRec))
@@ -304,21 +468,140 @@ func (us usersService) List(id *string, filters []*model.QueryFilter, limit *int
 	return record, nil
 }
 
+// Page returns a keyset paginated page of the current user's tenant users
+func (us usersService) Page(cu *models.User, filters []*model.QueryFilter, where *model.FilterGroup, args pagination.Args) ([]*models.User, *pagination.Page, error) {
+	if err := authz.Authorize(us.policy, cu, consts.Permissions.List, consts.EntityNames.Users, nil); err != nil {
+		return nil, nil, err
+	}
+	return us.userRepo.ForTenant(tenancy.ForUser(cu)).Page(filters, where, args)
+}
+
+// Count returns the number of users matching the filters
+func (us usersService) Count(cu *models.User, id *string, filters []*model.QueryFilter, where *model.FilterGroup) (int64, error) {
+	if err := authz.Authorize(us.policy, cu, consts.Permissions.List, consts.EntityNames.Users, nil); err != nil {
+		return 0, err
+	}
+	return us.userRepo.ForTenant(tenancy.ForUser(cu)).Count(id, filters, where)
+}
+
+// Subscribe returns the users of the topic's events, or only the ones of the
+// user with the id, as seen by the current user when each event comes
+func (us usersService) Subscribe(ctx context.Context, cu *models.User, topic string, id *string) (<-chan *models.User, error) {
+	events, err := us.events.Subscribe(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	users := make(chan *models.User)
+	go func() {
+		defer close(users)
+		for e := range events {
+			if id != nil && e.ID != *id {
+				continue
+			}
+			dbo, err := us.FindById(cu, e.ID)
+			if err != nil {
+				continue
+			}
+			select {
+			case users <- dbo:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return users, nil
+}
+
+// publish the mutation of the user, subscribers missing it doesn't fail the
+// mutation
+func (us usersService) publish(topic string, i *models.User) {
+	if err := us.events.Publish(context.Background(), pubsub.Event{Topic: topic, ID: i.ID.String()}); err != nil {
+		logger.Errorf("[UsersService] publish %s: %v", topic, err)
+	}
+}
+
+// IssueToken issues a db provider JWT for the user, with the user's active
+// organization as the org claim
 func (us usersService) IssueToken(u *models.User, cfg *utils.ServerConfig) (string, error) {
-	jwtToken := jwt.NewWithClaims(jwt.GetSigningMethod(cfg.JWT.Algorithm), auth.Claims{
+	claims := auth.Claims{
 		Email: u.Email,
 		StandardClaims: jwt.StandardClaims{
 			Id:        u.ID.String(),
 			Issuer:    consts.Providers.DB,
 			IssuedAt:  time.Now().UTC().Unix(),
 			NotBefore: time.Now().UTC().Unix(),
-			ExpiresAt: 365 * 24 * 60 * 60 * 1000,
+			ExpiresAt: time.Now().UTC().Add(365 * 24 * time.Hour).Unix(),
 		},
-	})
+	}
+	if u.ActiveOrganizationID != nil {
+		claims.Organization = u.ActiveOrganizationID.String()
+	}
+	jwtToken := jwt.NewWithClaims(jwt.GetSigningMethod(cfg.JWT.Algorithm), claims)
 
 	return jwtToken.SignedString([]byte(cfg.JWT.Secret))
 }
 
+func parseIDs(ids []string) ([]uuid.UUID, error) {
+	parsed := make([]uuid.UUID, len(ids))
+	for i, id := range ids {
+		var err error
+		if parsed[i], err = parseID(id); err != nil {
+			return nil, err
+		}
+	}
+	return parsed, nil
+}
+
+// credentialsOwned checks the current user can change the email and the
+// password of the user, their own or any as a platform admin
+func credentialsOwned(cu *models.User, dbo *models.User) error {
+	if dbo.ID != cu.ID && !cu.IsPlatformAdmin() {
+		return ErrCredentialsNotOwned
+	}
+	return nil
+}
+
+// mergeUserInput copies the fields of the input onto the stored user, hashing
+// the password the way the hooks do since the updates skip them
+func mergeUserInput(dbo *models.User, input model.UserInput, cu *models.User) (*models.User, error) {
+	if input.Password != nil {
+		if *input.Password == "" {
+			return nil, ErrEmptyPassword
+		}
+		pwd, err := generateHashFromPassword(*input.Password)
+		if err != nil {
+			return nil, err
+		}
+		dbo.Password = pwd
+	}
+	if input.Email != nil {
+		dbo.Email = *input.Email
+	}
+	if input.AvatarURL != nil {
+		dbo.AvatarURL = input.AvatarURL
+	}
+	if input.Name != nil {
+		dbo.Name = input.Name
+	}
+	if input.FirstName != nil {
+		dbo.FirstName = input.FirstName
+	}
+	if input.LastName != nil {
+		dbo.LastName = input.LastName
+	}
+	if input.NickName != nil {
+		dbo.NickName = input.NickName
+	}
+	if input.Description != nil {
+		dbo.Description = input.Description
+	}
+	if input.Location != nil {
+		dbo.Location = input.Location
+	}
+	dbo.UpdatedByID = &cu.ID
+	return dbo, nil
+}
+
 func generateHashFromPassword(password string) (string, error) {
 	if password != "" {
 		if pw, err := bcrypt.GenerateFromPassword([]byte(password), 11); err != nil {
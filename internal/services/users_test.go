@@ -0,0 +1,221 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/txbrown/gqlgen-api-starter/internal/gql/model"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/models"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/query"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/repositories"
+	"github.com/txbrown/gqlgen-api-starter/internal/orm/tenancy"
+	"github.com/txbrown/gqlgen-api-starter/internal/pubsub"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// fakeUsers keeps the users in memory, the methods the tests don't use panic
+type fakeUsers struct {
+	repositories.UsersRepository
+	users  map[uuid.UUID]models.User
+	limits []int
+}
+
+func newFakeUsers(users ...*models.User) *fakeUsers {
+	f := &fakeUsers{users: map[uuid.UUID]models.User{}}
+	for _, u := range users {
+		f.users[u.ID] = *u
+	}
+	return f
+}
+
+func (f *fakeUsers) ForTenant(t tenancy.Tenant) repositories.UsersRepository {
+	return f
+}
+
+func (f *fakeUsers) FindById(id uuid.UUID) (*models.User, error) {
+	u, ok := f.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &u, nil
+}
+
+func (f *fakeUsers) Update(i *models.User) error {
+	f.users[i.ID] = *i
+	return nil
+}
+
+func (f *fakeUsers) Search(id *string, filters []*model.QueryFilter, where *model.FilterGroup, limit *int, offset *int, sort []query.Sort) ([]*models.User, error) {
+	f.limits = append(f.limits, *limit)
+	results := []*models.User{}
+	for _, u := range f.users {
+		u := u
+		results = append(results, &u)
+	}
+	return results, nil
+}
+
+func newTestUsersService(t *testing.T, repo *fakeUsers) UsersService {
+	return NewUsersService(repo, nil, nil, testPolicy(t), pubsub.NewInProcess(1))
+}
+
+func storedUser() *models.User {
+	u := testUser(nil)
+	u.Email = "stored@example.com"
+	u.Password = "$2a$11$stored.hash"
+	u.FirstName = strPtr("Stored")
+	u.LastName = strPtr("Name")
+	return u
+}
+
+func TestUpdateKeepsTheFieldsNotGiven(t *testing.T) {
+	admin := testUser([]string{"admin"}, "update:users")
+	stored := storedUser()
+	repo := newFakeUsers(stored)
+	us := newTestUsersService(t, repo)
+
+	_, err := us.CreateUpdate(model.UserInput{FirstName: strPtr("Changed")}, true, admin, stored.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved := repo.users[stored.ID]
+	if *saved.FirstName != "Changed" {
+		t.Errorf("first name = %q, want Changed", *saved.FirstName)
+	}
+	if saved.Email != stored.Email || saved.Password != stored.Password || *saved.LastName != *stored.LastName {
+		t.Errorf("fields not given were changed: %+v", saved)
+	}
+}
+
+func TestUpdateHashesThePassword(t *testing.T) {
+	admin := testUser([]string{"admin"}, "update:users")
+	stored := storedUser()
+	repo := newFakeUsers(stored)
+	us := newTestUsersService(t, repo)
+
+	_, err := us.CreateUpdate(model.UserInput{Password: strPtr("new-password")}, true, admin, stored.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved := repo.users[stored.ID]
+	if saved.Password == "new-password" {
+		t.Fatal("password was stored in plain text")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(saved.Password), []byte("new-password")); err != nil {
+		t.Errorf("password hash doesn't match: %v", err)
+	}
+	if saved.Email != stored.Email {
+		t.Errorf("email = %q, want %q", saved.Email, stored.Email)
+	}
+}
+
+func TestUpdateProfileCredentialsOfOtherUsers(t *testing.T) {
+	stored := storedUser()
+	tests := []struct {
+		name  string
+		cu    *models.User
+		input model.UserProfileInput
+		err   error
+	}{
+		{
+			name:  "password by an owner",
+			cu:    testUser([]string{"owner"}, "update:users"),
+			input: model.UserProfileInput{Password: strPtr("new-password")},
+			err:   ErrCredentialsNotOwned,
+		},
+		{
+			name:  "email by an owner",
+			cu:    testUser([]string{"owner"}, "update:users"),
+			input: model.UserProfileInput{Email: strPtr("taken@example.com")},
+			err:   ErrCredentialsNotOwned,
+		},
+		{
+			name:  "name by an owner",
+			cu:    testUser([]string{"owner"}, "update:users"),
+			input: model.UserProfileInput{FirstName: strPtr("Changed")},
+		},
+		{
+			name:  "password by a platform admin",
+			cu:    testUser([]string{"admin"}, "update:users"),
+			input: model.UserProfileInput{Password: strPtr("new-password")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeUsers(stored, tt.cu)
+			us := newTestUsersService(t, repo)
+
+			_, err := us.UpdateProfile(tt.cu, stored.ID.String(), tt.input)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			saved := repo.users[stored.ID]
+			if tt.err != nil && (saved.Password != stored.Password || saved.Email != stored.Email) {
+				t.Error("credentials were changed")
+			}
+		})
+	}
+}
+
+func TestUpdateProfileOwnPasswordTakesTheCurrentOne(t *testing.T) {
+	cu := testUser(nil)
+	hash, _ := generateHashFromPassword("current-password")
+	cu.Password = hash
+	repo := newFakeUsers(cu)
+	us := newTestUsersService(t, repo)
+
+	_, err := us.UpdateProfile(cu, cu.ID.String(), model.UserProfileInput{Password: strPtr("new-password")})
+	if err != ErrInvalidCurrentPassword {
+		t.Fatalf("err = %v, want %v", err, ErrInvalidCurrentPassword)
+	}
+
+	_, err = us.UpdateProfile(cu, cu.ID.String(), model.UserProfileInput{
+		CurrentPassword: strPtr("current-password"),
+		Password:        strPtr("new-password"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(repo.users[cu.ID].Password), []byte("new-password")); err != nil {
+		t.Errorf("password hash doesn't match: %v", err)
+	}
+}
+
+func TestUsersListLimits(t *testing.T) {
+	cu := testUser(nil, "list:users")
+	tests := []struct {
+		name  string
+		limit *int
+		peek  int
+		err   error
+	}{
+		{name: "zero", limit: intPtr(0), err: query.ErrInvalidLimit},
+		{name: "negative", limit: intPtr(-1), err: query.ErrInvalidLimit},
+		{name: "null", limit: nil, peek: query.DefaultLimit + 1},
+		{name: "above the maximum", limit: intPtr(1000), peek: query.MaxLimit + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeUsers(storedUser())
+			us := newTestUsersService(t, repo)
+
+			_, err := us.List(cu, nil, nil, nil, tt.limit, nil, nil)
+			if err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				if len(repo.limits) != 0 {
+					t.Error("the repository was queried")
+				}
+				return
+			}
+			if len(repo.limits) != 1 || repo.limits[0] != tt.peek {
+				t.Errorf("limits = %v, want [%d]", repo.limits, tt.peek)
+			}
+		})
+	}
+}
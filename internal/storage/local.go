@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DownloadPath is the route the local driver's files are downloaded from
+const DownloadPath = "/files"
+
+// ErrInvalidSignature is returned for download URLs that were tampered with
+// or have expired
+var ErrInvalidSignature = errors.New("invalid or expired signature")
+
+// Local stores the files in a directory of the server, they are downloaded
+// from DownloadPath with URLs signed with the server's secret
+type Local struct {
+	dir     string
+	baseURL string
+	secret  []byte
+}
+
+// NewLocal returns the local driver, creating its directory if needed
+func NewLocal(dir string, baseURL string, secret string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("[Storage.NewLocal] %v", err)
+	}
+	return &Local{dir: dir, baseURL: baseURL, secret: []byte(secret)}, nil
+}
+
+// Put writes the file, a partially written file is removed
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, r); err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// Delete removes the file
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// SignedURL returns the download URL of the file
+func (l *Local) SignedURL(key string, filename string, expiry time.Duration) (string, error) {
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+	q := url.Values{}
+	q.Set("name", filename)
+	q.Set("expires", expires)
+	q.Set("signature", l.sign(key, filename, expires))
+	return l.baseURL + "/" + key + "?" + q.Encode(), nil
+}
+
+// Open verifies the signature of a download URL and opens its file
+func (l *Local) Open(key string, filename string, expires string, signature string) (*os.File, error) {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return nil, ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(l.sign(key, filename, expires))) {
+		return nil, ErrInvalidSignature
+	}
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (l *Local) sign(key string, filename string, expires string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(key + "\n" + filename + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// path returns the file of the key, keys can't leave the directory
+func (l *Local) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid storage key: %s", key)
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(clean)), nil
+}
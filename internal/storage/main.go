@@ -0,0 +1,36 @@
+// Package storage keeps the bytes of the uploaded files. The drivers only deal
+// with opaque keys, what the files are and who owns them is tracked in the
+// database
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+)
+
+// Storage stores the files under keys and hands out signed URLs to download
+// them, so the API never has to stream them itself
+type Storage interface {
+	// Put stores size bytes of r under the key
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Delete removes the key, deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL to download the key as filename, valid for the
+	// expiry
+	SignedURL(key string, filename string, expiry time.Duration) (string, error)
+}
+
+// New returns the storage driver of the config
+func New(cfg *utils.ServerConfig) (Storage, error) {
+	switch cfg.Storage.Driver {
+	case "local":
+		return NewLocal(cfg.Storage.LocalDir, cfg.SchemaVersionedEndpoint(DownloadPath), cfg.SessionSecret)
+	case "s3":
+		return NewS3(cfg.Spaces)
+	}
+	return nil, fmt.Errorf("[Storage.New] unknown storage driver: %s", cfg.Storage.Driver)
+}
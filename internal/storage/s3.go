@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+)
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	amzDateFormat   = "20060102T150405Z"
+)
+
+// S3 stores the files in a bucket of an S3 compatible service, e.g.
+// DigitalOcean Spaces. Objects are addressed path style and the requests are
+// signed with AWS Signature Version 4
+type S3 struct {
+	endpoint *url.URL
+	region   string
+	bucket   string
+	key      string
+	secret   string
+	client   *http.Client
+}
+
+// NewS3 returns the s3 driver of the config
+func NewS3(cfg utils.SpacesConfig) (*S3, error) {
+	if cfg.Key == "" || cfg.Secret == "" || cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("[Storage.NewS3] SPACES_KEY, SPACES_SECRET, SPACES_ENDPOINT and SPACES_BUCKET are required")
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("[Storage.NewS3] %v", err)
+	}
+	return &S3{
+		endpoint: endpoint,
+		region:   cfg.Region,
+		bucket:   cfg.Bucket,
+		key:      cfg.Key,
+		secret:   cfg.Secret,
+		client:   &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+// Put uploads the object, the payload is streamed unsigned
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key).String(), r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+	return s.do(req)
+}
+
+// Delete removes the object
+func (s *S3) Delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key).String(), nil)
+	if err != nil {
+		return err
+	}
+	return s.do(req)
+}
+
+// SignedURL returns a presigned GET URL of the object
+func (s *S3) SignedURL(key string, filename string, expiry time.Duration) (string, error) {
+	u := s.objectURL(key)
+	q := url.Values{}
+	q.Set("response-content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	presign(u, q, s.key, s.secret, s.region, time.Now(), expiry)
+	return u.String(), nil
+}
+
+func (s *S3) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + key
+	return &u
+}
+
+func (s *S3) do(req *http.Request) error {
+	sign(req, s.key, s.secret, s.region, time.Now())
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 && !(req.Method == http.MethodDelete && res.StatusCode == http.StatusNotFound) {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("[Storage.S3] %s %s: %s %s", req.Method, req.URL.Path, res.Status, body)
+	}
+	return nil
+}
+
+// sign adds the Authorization header of the request
+func sign(req *http.Request, key string, secret string, region string, now time.Time) {
+	now = now.UTC()
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", now.Format(amzDateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	names := []string{}
+	headers := map[string]string{}
+	for name, values := range req.Header {
+		n := strings.ToLower(name)
+		names = append(names, n)
+		headers[n] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	sort.Strings(names)
+	canonicalHeaders := ""
+	for _, n := range names {
+		canonicalHeaders += n + ":" + headers[n] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+	req.Header.Del("Host")
+
+	scope := credentialScope(now, region)
+	canonical := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+	signature := signature(secret, region, now, scope, canonical)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s", sigV4Algorithm, key, scope, signedHeaders, signature))
+}
+
+// presign adds the query signing a GET of the URL, with the extra query
+func presign(u *url.URL, q url.Values, key string, secret string, region string, now time.Time, expiry time.Duration) {
+	now = now.UTC()
+	scope := credentialScope(now, region)
+	q.Set("X-Amz-Algorithm", sigV4Algorithm)
+	q.Set("X-Amz-Credential", key+"/"+scope)
+	q.Set("X-Amz-Date", now.Format(amzDateFormat))
+	q.Set("X-Amz-Expires", strconv.Itoa(int(expiry.Seconds())))
+	q.Set("X-Amz-SignedHeaders", "host")
+
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		escapePath(u.Path),
+		canonicalQuery(q),
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	u.RawQuery = canonicalQuery(q) + "&X-Amz-Signature=" + signature(secret, region, now, scope, canonical)
+}
+
+func credentialScope(now time.Time, region string) string {
+	return now.Format("20060102") + "/" + region + "/s3/aws4_request"
+}
+
+func signature(secret string, region string, now time.Time, scope string, canonical string) string {
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := strings.Join([]string{sigV4Algorithm, now.Format(amzDateFormat), scope, hex.EncodeToString(hash[:])}, "\n")
+
+	k := hmacSHA256([]byte("AWS4"+secret), now.Format("20060102"))
+	k = hmacSHA256(k, region)
+	k = hmacSHA256(k, "s3")
+	k = hmacSHA256(k, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(k, toSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes the query sorted by key, as SigV4 expects
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := []string{}
+	for _, k := range keys {
+		values := append([]string{}, q[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, escape(k, true)+"="+escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func escapePath(path string) string {
+	return escape(path, false)
+}
+
+// escape URI encodes everything but the unreserved characters, and the
+// slashes of paths
+func escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
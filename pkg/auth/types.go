@@ -4,6 +4,7 @@ import "github.com/dgrijalva/jwt-go"
 
 // Claims JWT claims
 type Claims struct {
-	Email string `json:"email"`
+	Email        string `json:"email"`
+	Organization string `json:"org,omitempty"` // Active organization id
 	jwt.StandardClaims
 }
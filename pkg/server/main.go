@@ -15,7 +15,9 @@ func Run(serverconf *utils.ServerConfig, services *services.Services) {
 	InitalizeAuthProviders(serverconf)
 
 	// Routes and Handlers
-	RegisterRoutes(serverconf, r, services)
+	if err := RegisterRoutes(serverconf, r, services); err != nil {
+		logger.Fatal(err)
+	}
 
 	// Inform the user where the server is listening
 	logger.Info("Running @ " + serverconf.SchemaVersionedEndpoint(""))
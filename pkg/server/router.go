@@ -34,6 +34,10 @@ func RegisterRoutes(cfg *utils.ServerConfig, r *gin.Engine, services *services.S
 	if err = routes.GraphQL(cfg, r, services); err != nil {
 		return err
 	}
+	// File download routes
+	if err = routes.Files(cfg, r, services); err != nil {
+		return err
+	}
 	// Miscellaneous routes
 	if err = routes.Misc(cfg, r, services); err != nil {
 		return err
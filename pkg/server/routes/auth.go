@@ -12,7 +12,8 @@ func Auth(cfg *utils.ServerConfig, r *gin.Engine, services *services.Services) e
 	// OAuth handlers
 	g := r.Group(cfg.VersionedEndpoint("/auth"))
 	g.GET("/:provider", auth.Begin())
-	g.GET("/:provider/callback", auth.Callback(cfg, services.UsersService))
+	g.GET("/:provider/callback", auth.Callback(cfg, services.UsersService, services.AuditService))
+	g.GET("/:provider/logout", auth.Logout(cfg, services.AuditService))
 	// g.GET(:provider/refresh", auth.Refresh(cfg, orm))
 	return nil
 }
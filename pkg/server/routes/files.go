@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/txbrown/gqlgen-api-starter/internal/handlers"
+	"github.com/txbrown/gqlgen-api-starter/internal/logger"
+	"github.com/txbrown/gqlgen-api-starter/internal/services"
+	"github.com/txbrown/gqlgen-api-starter/internal/storage"
+	"github.com/txbrown/gqlgen-api-starter/pkg/utils"
+)
+
+// Files routes, only the local storage downloads through the server
+func Files(cfg *utils.ServerConfig, r *gin.Engine, services *services.Services) error {
+	if cfg.Storage.Driver != "local" {
+		return nil
+	}
+	path := cfg.VersionedEndpoint(storage.DownloadPath)
+	r.GET(path+"/*key", handlers.Download(services.FilesService))
+	logger.Info("Files @ ", path)
+	return nil
+}
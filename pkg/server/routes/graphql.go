@@ -16,13 +16,23 @@ func GraphQL(cfg *utils.ServerConfig, r *gin.Engine, services *services.Services
 	pgqlPath := cfg.GraphQL.PlaygroundPath
 	g := r.Group(gqlPath)
 
-	// GraphQL handler
-	g.POST("", auth.Middleware(g.BasePath(), cfg, services.UsersService), handlers.GraphqlHandler(services))
+	// GraphQL handler, GET upgrades to websockets for subscriptions
+	m := auth.Middleware(g.BasePath(), cfg, services.UsersService, services.OrganizationsService, services.GroupsService, services.AuditService)
+	h, err := handlers.GraphqlHandler(cfg, services)
+	if err != nil {
+		return err
+	}
+	g.POST("", m, h)
+	g.GET("", m, h)
 	logger.Info("GraphQL @ ", gqlPath)
-	// Playground handler
-	if cfg.GraphQL.IsPlaygroundEnabled {
-		logger.Info("GraphQL Playground @ ", g.BasePath()+pgqlPath)
-		g.GET(pgqlPath, handlers.PlaygroundHandler(g.BasePath()))
+	// Playground handler, behind the auth so the roles can open it
+	if cfg.GraphQL.IsPlaygroundEnabled || len(cfg.GraphQL.PlaygroundRoles) > 0 {
+		ph, err := handlers.PlaygroundHandler(cfg, g.BasePath())
+		if err != nil {
+			return err
+		}
+		logger.Info("GraphQL ", cfg.GraphQL.IDE, " @ ", g.BasePath()+pgqlPath)
+		g.GET(pgqlPath, m, ph)
 	}
 
 	return nil
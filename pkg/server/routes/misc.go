@@ -13,6 +13,6 @@ func Misc(cfg *utils.ServerConfig, r *gin.Engine, services *services.Services) e
 	// Simple keep-alive/ping handler
 	r.GET(cfg.VersionedEndpoint("/ping"), handlers.Ping())
 	r.GET(cfg.VersionedEndpoint("/secure-ping"),
-		middleware.Middleware(cfg.VersionedEndpoint("/secure-ping"), cfg, services.UsersService), handlers.Ping())
+		middleware.Middleware(cfg.VersionedEndpoint("/secure-ping"), cfg, services.UsersService, services.OrganizationsService, services.GroupsService, services.AuditService), handlers.Ping())
 	return nil
 }
@@ -37,6 +37,11 @@ type entitynames struct {
 	Feedback        string
 	Trophy          string
 	UserAPIKeys     string
+	Products        string
+	Organizations   string
+	OrgMembers      string
+	AuditEntries    string
+	Groups          string
 }
 
 type role struct {
@@ -85,6 +90,11 @@ var (
 		Feedback:        "Feedbacks",
 		Trophy:          "Trophies",
 		UserAPIKeys:     "UserAPIKeys",
+		Products:        "Products",
+		Organizations:   "Organizations",
+		OrgMembers:      "OrganizationMembers",
+		AuditEntries:    "AuditEntries",
+		Groups:          "Groups",
 	}
 	// Dialects are definition of databases
 	Dialects = dialects{
@@ -110,6 +120,9 @@ var (
 	}
 
 	NestedFmt = "%s.%s"
+
+	// PlatformAdminRole is the role that can act across organizations
+	PlatformAdminRole = "admin"
 )
 
 // GetTableName gets the db normalized tablename
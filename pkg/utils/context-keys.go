@@ -2,14 +2,22 @@ package utils
 
 // ContextKeys holds the context keys throught the project
 type ContextKeys struct {
-	ProviderCtxKey ContextKey // Provider in Auth
-	UserCtxKey     ContextKey // User db object in Auth
+	ProviderCtxKey       ContextKey // Provider in Auth
+	UserCtxKey           ContextKey // User db object in Auth
+	ImpersonatorCtxKey   ContextKey // User db object of an admin impersonating UserCtxKey
+	ClientIPCtxKey       ContextKey // IP of the client making the request
+	APIKeyCtxKey         ContextKey // UserAPIKey db object the request was authed with
+	ResponseHeaderCtxKey ContextKey // http.Header of the response to the request
 }
 
 var (
 	// ProjectContextKeys the project's context keys
 	ProjectContextKeys = ContextKeys{
-		ProviderCtxKey: "provider",
-		UserCtxKey:     "auth-user",
+		ProviderCtxKey:       "provider",
+		UserCtxKey:           "auth-user",
+		ImpersonatorCtxKey:   "auth-impersonator",
+		ClientIPCtxKey:       "client-ip",
+		APIKeyCtxKey:         "auth-api-key",
+		ResponseHeaderCtxKey: "response-header",
 	}
-)
\ No newline at end of file
+)
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // MustGet will return the env or panic if it is not present
@@ -27,3 +28,50 @@ func MustGetBool(k string) bool {
 	}
 	return b
 }
+
+// GetDefault will return the env or the fallback value if it is not present
+func GetDefault(k string, fallback string) string {
+	if v := os.Getenv(k); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// GetDefaultInt will return the env as int or the fallback value if it is not
+// present
+func GetDefaultInt(k string, fallback int) int {
+	v := os.Getenv(k)
+	if v == "" {
+		return fallback
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		log.Panicln("ENV err: [" + k + "]\n" + err.Error())
+	}
+	return i
+}
+
+// GetDefaultBool will return the env as boolean or the fallback value if it is
+// not present
+func GetDefaultBool(k string, fallback bool) bool {
+	if os.Getenv(k) == "" {
+		return fallback
+	}
+	return MustGetBool(k)
+}
+
+// GetDefaultList will return the env as a comma separated list or the
+// fallback value if it is not present
+func GetDefaultList(k string, fallback []string) []string {
+	v := os.Getenv(k)
+	if v == "" {
+		return fallback
+	}
+	list := []string{}
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIP returns the IP of the client of the request. The X-Forwarded-For
+// and X-Real-IP headers are only read when the request comes from one of the
+// trusted proxies, IPs or CIDRs, since any client can send them
+func ClientIP(r *http.Request, trustedProxies []string) string {
+	remote, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
+	if err != nil {
+		remote = strings.TrimSpace(r.RemoteAddr)
+	}
+	if !isTrustedProxy(remote, trustedProxies) {
+		return remote
+	}
+	// each proxy appends the address it got the request from, the first one
+	// from the right that isn't a trusted proxy is the client
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(forwarded[i])
+		if ip != "" && !isTrustedProxy(ip, trustedProxies) {
+			return ip
+		}
+	}
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+		return ip
+	}
+	return remote
+}
+
+func isTrustedProxy(ip string, trustedProxies []string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, proxy := range trustedProxies {
+		if strings.Contains(proxy, "/") {
+			if _, network, err := net.ParseCIDR(proxy); err == nil && network.Contains(addr) {
+				return true
+			}
+		} else if p := net.ParseIP(proxy); p != nil && p.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"strings"
+	"time"
 )
 
 // ContextKey defines a type for context keys shared in the app
@@ -9,22 +10,41 @@ type ContextKey string
 
 // ServerConfig defines the configuration for the server
 type ServerConfig struct {
+	Environment   string // development or production
 	Host          string
 	Port          string
 	URISchema     string
 	Version       string
 	SessionSecret string
-	JWT           JWTConfig
-	GraphQL       GQLConfig
-	Database      DBConfig
-	AuthProviders []AuthProvider
-	Spaces        SpacesConfig
+	// TrustedProxies are the IPs or CIDRs of the proxies whose forwarding
+	// headers are trusted for the client's IP
+	TrustedProxies []string
+	JWT            JWTConfig
+	GraphQL        GQLConfig
+	Database       DBConfig
+	AuthProviders  []AuthProvider
+	Spaces         SpacesConfig
+	Storage        StorageConfig
+	RBAC           RBACConfig
 }
 
+// SpacesConfig defines the S3 compatible object storage (DigitalOcean Spaces,
+// S3, MinIO...) uploads are stored in by the s3 storage driver
 type SpacesConfig struct {
 	Key      string
 	Secret   string
 	Endpoint string
+	Region   string
+	Bucket   string
+}
+
+// StorageConfig defines where uploaded files go and what is accepted
+type StorageConfig struct {
+	Driver              string // local or s3
+	LocalDir            string // Directory of the local driver
+	MaxUploadSize       int64  // Bytes per file
+	AllowedContentTypes []string
+	URLExpiry           time.Duration // Lifetime of the signed download URLs
 }
 
 //JWTConfig defines the options for JWT tokens
@@ -35,9 +55,47 @@ type JWTConfig struct {
 
 // GQLConfig defines the configuration for the GQL Server
 type GQLConfig struct {
-	Path                string
-	PlaygroundPath      string
-	IsPlaygroundEnabled bool
+	Path                 string
+	PlaygroundPath       string
+	IsPlaygroundEnabled  bool     // The IDE is open to everyone, not only PlaygroundRoles
+	PlaygroundRoles      []string // Roles of the users who can open the IDE
+	IDE                  string   // playground, graphiql or sandbox
+	IntrospectionEnabled bool     // Introspection is open to everyone, not only IntrospectionRoles
+	IntrospectionRoles   []string // Roles of the users who can introspect the schema
+	QueryCacheSize       int      // Parsed and validated documents kept in memory
+	Transports           []string // post, get, multipart and websocket
+	Extensions           []string // Names registered in internal/gql/extensions
+	FederationEnabled    bool     // Serves the schema as an Apollo Federation subgraph
+	Limits               GQLLimitsConfig
+	RateLimit            GQLRateLimitConfig
+	PersistedQueries     GQLPersistedQueriesConfig
+}
+
+// GQLLimitsConfig defines the limits operations are checked against before
+// they are executed, 0 disables a limit
+type GQLLimitsConfig struct {
+	MaxDepth             int
+	ComplexityLimit      int
+	AdminComplexityLimit int // Budget of the platform admins
+}
+
+// GQLRateLimitConfig defines the token buckets the operations are charged
+// their complexity against, one per API key, user or client IP
+type GQLRateLimitConfig struct {
+	// Tiers are role:capacity:refillPerMinute, the anonymous and default
+	// tiers are for the clients and users without a role's tier
+	Tiers []string
+	// FailOpen lets the operations through when the buckets' store fails,
+	// otherwise they are refused
+	FailOpen bool
+}
+
+// GQLPersistedQueriesConfig defines how the server serves documents by hash
+type GQLPersistedQueriesConfig struct {
+	Enabled     bool // Automatic persisted queries
+	CacheSize   int
+	SharedStore bool // Backs the in-memory cache with the database
+	TrustedOnly bool // Only executes the documents imported from manifests
 }
 
 // DBConfig defines the configuration for the DB config
@@ -49,6 +107,11 @@ type DBConfig struct {
 	AutoMigrate bool
 }
 
+// RBACConfig defines the configuration for the RBAC policy
+type RBACConfig struct {
+	PolicyFile string
+}
+
 // AuthProvider defines the configuration for the Goth config
 type AuthProvider struct {
 	Provider  string
@@ -58,6 +121,12 @@ type AuthProvider struct {
 	Scopes    []string
 }
 
+// IsProduction reports whether the server runs in production, where internal
+// details are not shown to clients
+func (s *ServerConfig) IsProduction() bool {
+	return s.Environment == "production"
+}
+
 // ListenEndpoint builds the endpoint string (host + port)
 func (s *ServerConfig) ListenEndpoint() string {
 	if s.Port == "80" {
@@ -80,20 +149,48 @@ func (s *ServerConfig) SchemaVersionedEndpoint(path string) string {
 }
 
 func NewServerConfig() *ServerConfig {
+	environment := GetDefault("SERVER_ENVIRONMENT", "development")
+	// The developer tools are open outside of production, and to the admins
+	devTools := environment != "production"
 	var serverconf = &ServerConfig{
-		Host:          MustGet("SERVER_HOST"),
-		Port:          MustGet("PORT"),
-		URISchema:     MustGet("SERVER_URI_SCHEMA"),
-		Version:       MustGet("SERVER_PATH_VERSION"),
-		SessionSecret: MustGet("SESSION_SECRET"),
+		Environment:    environment,
+		Host:           MustGet("SERVER_HOST"),
+		Port:           MustGet("PORT"),
+		URISchema:      MustGet("SERVER_URI_SCHEMA"),
+		Version:        MustGet("SERVER_PATH_VERSION"),
+		SessionSecret:  MustGet("SESSION_SECRET"),
+		TrustedProxies: GetDefaultList("SERVER_TRUSTED_PROXIES", []string{}),
 		JWT: JWTConfig{
 			Secret:    MustGet("AUTH_JWT_SECRET"),
 			Algorithm: MustGet("AUTH_JWT_SIGNING_ALGORITHM"),
 		},
 		GraphQL: GQLConfig{
-			Path:                MustGet("GQL_SERVER_GRAPHQL_PATH"),
-			PlaygroundPath:      MustGet("GQL_SERVER_GRAPHQL_PLAYGROUND_PATH"),
-			IsPlaygroundEnabled: MustGetBool("GQL_SERVER_GRAPHQL_PLAYGROUND_ENABLED"),
+			Path:                 MustGet("GQL_SERVER_GRAPHQL_PATH"),
+			PlaygroundPath:       MustGet("GQL_SERVER_GRAPHQL_PLAYGROUND_PATH"),
+			IsPlaygroundEnabled:  GetDefaultBool("GQL_SERVER_GRAPHQL_PLAYGROUND_ENABLED", devTools),
+			PlaygroundRoles:      GetDefaultList("GQL_SERVER_GRAPHQL_PLAYGROUND_ROLES", []string{"admin"}),
+			IDE:                  GetDefault("GQL_SERVER_GRAPHQL_IDE", "playground"),
+			IntrospectionEnabled: GetDefaultBool("GQL_SERVER_INTROSPECTION_ENABLED", devTools),
+			IntrospectionRoles:   GetDefaultList("GQL_SERVER_INTROSPECTION_ROLES", []string{"admin"}),
+			QueryCacheSize:       GetDefaultInt("GQL_SERVER_QUERY_CACHE_SIZE", 1000),
+			Transports:           GetDefaultList("GQL_SERVER_TRANSPORTS", []string{"post", "get", "multipart", "websocket"}),
+			Extensions:           GetDefaultList("GQL_SERVER_EXTENSIONS", []string{"persisted_queries", "depth_limit", "complexity_limit", "rate_limit"}),
+			FederationEnabled:    GetDefaultBool("GQL_SERVER_FEDERATION_ENABLED", false),
+			Limits: GQLLimitsConfig{
+				MaxDepth:             GetDefaultInt("GQL_SERVER_MAX_DEPTH", 10),
+				ComplexityLimit:      GetDefaultInt("GQL_SERVER_COMPLEXITY_LIMIT", 5000),
+				AdminComplexityLimit: GetDefaultInt("GQL_SERVER_ADMIN_COMPLEXITY_LIMIT", 50000),
+			},
+			RateLimit: GQLRateLimitConfig{
+				Tiers:    GetDefaultList("GQL_SERVER_RATE_LIMIT_TIERS", []string{"anonymous:10000:10000", "default:50000:50000"}),
+				FailOpen: GetDefaultBool("GQL_SERVER_RATE_LIMIT_FAIL_OPEN", false),
+			},
+			PersistedQueries: GQLPersistedQueriesConfig{
+				Enabled:     GetDefaultBool("GQL_SERVER_APQ_ENABLED", true),
+				CacheSize:   GetDefaultInt("GQL_SERVER_APQ_CACHE_SIZE", 1000),
+				SharedStore: GetDefaultBool("GQL_SERVER_APQ_SHARED_STORE", false),
+				TrustedOnly: GetDefaultBool("GQL_SERVER_TRUSTED_DOCUMENTS_ONLY", false),
+			},
 		},
 		Database: DBConfig{
 			Dialect:     MustGet("GORM_DIALECT"),
@@ -127,9 +224,21 @@ func NewServerConfig() *ServerConfig {
 			},
 		},
 		Spaces: SpacesConfig{
-			Key:      MustGet("SPACES_KEY"),
-			Secret:   MustGet("SPACES_SECRET"),
-			Endpoint: MustGet("SPACES_ENDPOINT"),
+			Key:      GetDefault("SPACES_KEY", ""),
+			Secret:   GetDefault("SPACES_SECRET", ""),
+			Endpoint: GetDefault("SPACES_ENDPOINT", ""),
+			Region:   GetDefault("SPACES_REGION", "us-east-1"),
+			Bucket:   GetDefault("SPACES_BUCKET", ""),
+		},
+		Storage: StorageConfig{
+			Driver:              GetDefault("STORAGE_DRIVER", "local"),
+			LocalDir:            GetDefault("STORAGE_LOCAL_DIR", "uploads"),
+			MaxUploadSize:       int64(GetDefaultInt("STORAGE_MAX_UPLOAD_SIZE", 10<<20)),
+			AllowedContentTypes: strings.Split(GetDefault("STORAGE_ALLOWED_CONTENT_TYPES", "image/jpeg,image/png,image/gif,image/webp,application/pdf,text/plain"), ","),
+			URLExpiry:           time.Duration(GetDefaultInt("STORAGE_URL_EXPIRY_SECONDS", 900)) * time.Second,
+		},
+		RBAC: RBACConfig{
+			PolicyFile: GetDefault("RBAC_POLICY_FILE", "rbac.yml"),
 		},
 	}
 
@@ -137,6 +246,7 @@ func NewServerConfig() *ServerConfig {
 }
 
 var TestServerconf = &ServerConfig{
+	Environment:   "test",
 	Host:          "localhost",
 	Port:          "7777",
 	URISchema:     "http",
@@ -147,9 +257,26 @@ var TestServerconf = &ServerConfig{
 		Algorithm: "HS512",
 	},
 	GraphQL: GQLConfig{
-		Path:                "/graphql",
-		PlaygroundPath:      "/playground",
-		IsPlaygroundEnabled: false,
+		Path:                 "/graphql",
+		PlaygroundPath:       "/playground",
+		IsPlaygroundEnabled:  false,
+		IDE:                  "playground",
+		IntrospectionEnabled: true,
+		QueryCacheSize:       1000,
+		Transports:           []string{"post", "get", "multipart", "websocket"},
+		Extensions:           []string{"persisted_queries", "depth_limit", "complexity_limit", "rate_limit"},
+		Limits: GQLLimitsConfig{
+			MaxDepth:             10,
+			ComplexityLimit:      5000,
+			AdminComplexityLimit: 50000,
+		},
+		RateLimit: GQLRateLimitConfig{
+			Tiers: []string{"anonymous:10000:10000", "default:50000:50000"},
+		},
+		PersistedQueries: GQLPersistedQueriesConfig{
+			Enabled:   true,
+			CacheSize: 1000,
+		},
 	},
 	Database: DBConfig{
 		Dialect:     "postgres",
@@ -169,5 +296,17 @@ var TestServerconf = &ServerConfig{
 		Key:      "key",
 		Secret:   "secret",
 		Endpoint: "",
+		Region:   "us-east-1",
+		Bucket:   "",
+	},
+	Storage: StorageConfig{
+		Driver:              "local",
+		LocalDir:            "uploads",
+		MaxUploadSize:       10 << 20,
+		AllowedContentTypes: []string{"image/jpeg", "image/png", "application/pdf", "text/plain"},
+		URLExpiry:           15 * time.Minute,
+	},
+	RBAC: RBACConfig{
+		PolicyFile: "rbac.yml",
 	},
 }